@@ -0,0 +1,125 @@
+package main
+
+import (
+	"bufio"
+	"errors"
+	"io"
+	"log"
+	"net"
+	"sync"
+
+	"github.com/roman-mazur/architecture-practice-4-template/datastore"
+	"github.com/roman-mazur/architecture-practice-4-template/dbproto"
+)
+
+// binaryServer обслуговує бінарний протокол і відстежує прийняті з'єднання,
+// щоб під час зупинки дочекатися їх завершення до закриття сховища.
+type binaryServer struct {
+	ln net.Listener
+	db *datastore.Db
+
+	mu     sync.Mutex
+	conns  map[net.Conn]struct{}
+	closed bool
+	wg     sync.WaitGroup
+}
+
+func newBinaryServer(ln net.Listener, db *datastore.Db) *binaryServer {
+	return &binaryServer{ln: ln, db: db, conns: make(map[net.Conn]struct{})}
+}
+
+// serve приймає з'єднання, доки сервер не буде закрито.
+func (s *binaryServer) serve() {
+	for {
+		conn, err := s.ln.Accept()
+		if err != nil {
+			if !errors.Is(err, net.ErrClosed) {
+				log.Printf("Binary listener failed: %s", err)
+			}
+			return
+		}
+		s.mu.Lock()
+		if s.closed {
+			s.mu.Unlock()
+			_ = conn.Close()
+			return
+		}
+		s.conns[conn] = struct{}{}
+		s.wg.Add(1)
+		s.mu.Unlock()
+
+		go func() {
+			defer s.wg.Done()
+			handleBinaryConn(conn, s.db)
+			s.mu.Lock()
+			delete(s.conns, conn)
+			s.mu.Unlock()
+		}()
+	}
+}
+
+// Close припиняє приймати з'єднання, закриває відкриті й чекає, доки запити,
+// що вже виконуються, завершаться. Після цього сховище можна закривати.
+func (s *binaryServer) Close() {
+	s.mu.Lock()
+	s.closed = true
+	_ = s.ln.Close()
+	for conn := range s.conns {
+		_ = conn.Close()
+	}
+	s.mu.Unlock()
+	s.wg.Wait()
+}
+
+func handleBinaryConn(conn net.Conn, db *datastore.Db) {
+	defer conn.Close()
+	r := bufio.NewReader(conn)
+	w := bufio.NewWriter(conn)
+
+	for {
+		req, err := dbproto.ReadRequest(r)
+		if err != nil {
+			if !errors.Is(err, io.EOF) {
+				log.Printf("Binary protocol error from %s: %s", conn.RemoteAddr(), err)
+			}
+			return
+		}
+
+		resp := execBinary(db, req)
+		if _, err := w.Write(resp.Encode()); err != nil {
+			return
+		}
+		// Поки в буфері є наступні запити конвеєра, відповіді накопичуємо.
+		if r.Buffered() == 0 {
+			if err := w.Flush(); err != nil {
+				return
+			}
+		}
+	}
+}
+
+func execBinary(db *datastore.Db, req dbproto.Request) dbproto.Response {
+	var (
+		value string
+		err   error
+	)
+	switch req.Op {
+	case dbproto.OpGet:
+		value, err = db.Get(req.Key)
+	case dbproto.OpPut:
+		err = db.Put(req.Key, req.Value)
+	case dbproto.OpDelete:
+		err = db.Delete(req.Key)
+	default:
+		return dbproto.Response{Status: dbproto.StatusError, Value: "unknown operation"}
+	}
+
+	switch {
+	case errors.Is(err, datastore.ErrNotFound):
+		return dbproto.Response{Status: dbproto.StatusNotFound}
+	case err != nil:
+		log.Printf("Error executing %c %s: %s", req.Op, req.Key, err)
+		return dbproto.Response{Status: dbproto.StatusError, Value: err.Error()}
+	}
+	return dbproto.Response{Status: dbproto.StatusOK, Value: value}
+}
@@ -0,0 +1,216 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/roman-mazur/architecture-practice-4-template/datastore"
+	"github.com/roman-mazur/architecture-practice-4-template/dbproto"
+)
+
+func openTestDb(tb testing.TB) *datastore.Db {
+	tb.Helper()
+	db, err := datastore.Open(tb.TempDir())
+	if err != nil {
+		tb.Fatal(err)
+	}
+	tb.Cleanup(func() { _ = db.Close() })
+	return db
+}
+
+func startBinary(tb testing.TB, db *datastore.Db) *dbproto.Client {
+	tb.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		tb.Fatal(err)
+	}
+	s := newBinaryServer(ln, db)
+	go s.serve()
+	tb.Cleanup(s.Close)
+
+	c, err := dbproto.Dial(ln.Addr().String(), time.Second)
+	if err != nil {
+		tb.Fatal(err)
+	}
+	tb.Cleanup(func() { _ = c.Close() })
+	return c
+}
+
+func TestBinary_Pipeline(t *testing.T) {
+	c := startBinary(t, openTestDb(t))
+
+	var reqs []dbproto.Request
+	for i := 0; i < 50; i++ {
+		reqs = append(reqs, dbproto.Request{Op: dbproto.OpPut, Key: "k" + strconv.Itoa(i), Value: "v" + strconv.Itoa(i)})
+	}
+	for i := 0; i < 50; i++ {
+		reqs = append(reqs, dbproto.Request{Op: dbproto.OpGet, Key: "k" + strconv.Itoa(i)})
+	}
+	reqs = append(reqs, dbproto.Request{Op: dbproto.OpGet, Key: "missing"}, dbproto.Request{Op: 'X'})
+
+	res, err := c.Pipeline(reqs)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 50; i++ {
+		if err := res[i].Err(); err != nil {
+			t.Fatalf("put %d: %s", i, err)
+		}
+		if got := res[50+i]; got.Status != dbproto.StatusOK || got.Value != "v"+strconv.Itoa(i) {
+			t.Errorf("get %d = %v", i, got)
+		}
+	}
+	if res[100].Status != dbproto.StatusNotFound {
+		t.Errorf("expected not found status, got %v", res[100])
+	}
+	if res[101].Status != dbproto.StatusError {
+		t.Errorf("expected error status for unknown op, got %v", res[101])
+	}
+}
+
+func TestBinary_Client(t *testing.T) {
+	c := startBinary(t, openTestDb(t))
+
+	if err := c.Put("key", "value"); err != nil {
+		t.Fatal(err)
+	}
+	if v, err := c.Get("key"); err != nil || v != "value" {
+		t.Fatalf("Get() = %q, %v", v, err)
+	}
+	if err := c.Delete("key"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := c.Get("key"); !errors.Is(err, dbproto.ErrNotFound) {
+		t.Errorf("expected ErrNotFound, got %v", err)
+	}
+}
+
+func TestBinary_CloseWaitsForConnections(t *testing.T) {
+	db, err := datastore.Open(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := newBinaryServer(ln, db)
+	go s.serve()
+
+	c, err := dbproto.Dial(ln.Addr().String(), time.Second)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = c.Close() })
+	if err := c.Put("key", "value"); err != nil {
+		t.Fatal(err)
+	}
+
+	// Після Close жоден обробник не звертається до закритого сховища.
+	s.Close()
+	if err := db.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.Put("key", "other"); err == nil {
+		t.Error("expected the connection to be closed")
+	}
+}
+
+const benchKeys = 100
+
+func fillBench(b *testing.B, db *datastore.Db) {
+	b.Helper()
+	for i := 0; i < benchKeys; i++ {
+		if err := db.Put("key-"+strconv.Itoa(i), "value-"+strconv.Itoa(i)); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkGet_JSON(b *testing.B) {
+	db := openTestDb(b)
+	fillBench(b, db)
+	srv := httptest.NewServer(dbHandler(db))
+	b.Cleanup(srv.Close)
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		resp, err := http.Get(fmt.Sprintf("%s/db/key-%d", srv.URL, i%benchKeys))
+		if err != nil {
+			b.Fatal(err)
+		}
+		var body Response
+		err = json.NewDecoder(resp.Body).Decode(&body)
+		resp.Body.Close()
+		if err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkPut_JSON(b *testing.B) {
+	db := openTestDb(b)
+	srv := httptest.NewServer(dbHandler(db))
+	b.Cleanup(srv.Close)
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		payload, _ := json.Marshal(map[string]string{"value": "value"})
+		resp, err := http.Post(fmt.Sprintf("%s/db/key-%d", srv.URL, i%benchKeys), "application/json", bytes.NewReader(payload))
+		if err != nil {
+			b.Fatal(err)
+		}
+		resp.Body.Close()
+	}
+}
+
+func BenchmarkGet_Binary(b *testing.B) {
+	db := openTestDb(b)
+	fillBench(b, db)
+	c := startBinary(b, db)
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := c.Get("key-" + strconv.Itoa(i%benchKeys)); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkPut_Binary(b *testing.B) {
+	c := startBinary(b, openTestDb(b))
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if err := c.Put("key-"+strconv.Itoa(i%benchKeys), "value"); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkGet_BinaryPipelined(b *testing.B) {
+	db := openTestDb(b)
+	fillBench(b, db)
+	c := startBinary(b, db)
+
+	const depth = 32
+	reqs := make([]dbproto.Request, depth)
+	for i := range reqs {
+		reqs[i] = dbproto.Request{Op: dbproto.OpGet, Key: "key-" + strconv.Itoa(i%benchKeys)}
+	}
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i += depth {
+		if _, err := c.Pipeline(reqs); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
@@ -0,0 +1,140 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/roman-mazur/architecture-practice-4-template/datastore"
+)
+
+const dbPathPrefix = "/db/"
+
+// Коди помилок у тілі відповіді.
+const (
+	codeNotFound         = "not_found"
+	codeInvalidKey       = "invalid_key"
+	codeInvalidBody      = "invalid_body"
+	codeMethodNotAllowed = "method_not_allowed"
+	codeExists           = "exists"
+	codeInternal         = "internal"
+)
+
+type Response struct {
+	Key   string `json:"key"`
+	Value string `json:"value"`
+}
+
+type ErrorResponse struct {
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
+// dbHandler обслуговує /db/<key>. Ключ — увесь декодований шлях після префікса,
+// тож він може містити слеші (/db/a/b або /db/a%2Fb означають ключ "a/b").
+func dbHandler(db *datastore.Db) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		key := strings.TrimPrefix(r.URL.Path, dbPathPrefix)
+		if key == "" || key == r.URL.Path {
+			writeError(w, http.StatusBadRequest, codeInvalidKey, "key must not be empty")
+			return
+		}
+
+		switch r.Method {
+		case http.MethodGet:
+			handleGet(db, key, w)
+		case http.MethodPost, http.MethodPut:
+			handlePut(db, key, w, r)
+		case http.MethodDelete:
+			handleDelete(db, key, w)
+		default:
+			w.Header().Set("Allow", "GET, POST, PUT, DELETE")
+			writeError(w, http.StatusMethodNotAllowed, codeMethodNotAllowed, "method "+r.Method+" is not allowed")
+		}
+	}
+}
+
+func handleGet(db *datastore.Db, key string, w http.ResponseWriter) {
+	value, err := db.Get(key)
+	if err != nil {
+		writeStoreError(w, key, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, Response{Key: key, Value: value})
+}
+
+// handlePut відповідає 201 для нового ключа та 204 для перезапису існуючого.
+// З заголовком "If-None-Match: *" існуючий ключ не перезаписується, а запит
+// отримує 412.
+func handlePut(db *datastore.Db, key string, w http.ResponseWriter, r *http.Request) {
+	var reqBody struct {
+		Value *string `json:"value"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&reqBody); err != nil {
+		writeError(w, http.StatusBadRequest, codeInvalidBody, "invalid request body: "+err.Error())
+		return
+	}
+	if reqBody.Value == nil {
+		writeError(w, http.StatusBadRequest, codeInvalidBody, `request body must contain "value"`)
+		return
+	}
+
+	ifAbsent := r.Header.Get("If-None-Match") == "*"
+	var existed bool
+	var err error
+	if ifAbsent {
+		var stored bool
+		stored, err = db.PutIfAbsent(key, *reqBody.Value)
+		existed = !stored
+	} else {
+		existed, err = db.Upsert(key, *reqBody.Value)
+	}
+	if err != nil {
+		writeStoreError(w, key, err)
+		return
+	}
+	if existed && ifAbsent {
+		writeError(w, http.StatusPreconditionFailed, codeExists, "key "+key+" already exists")
+		return
+	}
+
+	if existed {
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+	w.Header().Set("Location", dbPathPrefix+url.PathEscape(key))
+	writeJSON(w, http.StatusCreated, Response{Key: key, Value: *reqBody.Value})
+}
+
+func handleDelete(db *datastore.Db, key string, w http.ResponseWriter) {
+	if err := db.Delete(key); err != nil {
+		writeStoreError(w, key, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// writeStoreError відрізняє відсутній ключ (404) від збою сховища (500).
+func writeStoreError(w http.ResponseWriter, key string, err error) {
+	if errors.Is(err, datastore.ErrNotFound) {
+		writeError(w, http.StatusNotFound, codeNotFound, "key "+key+" does not exist")
+		return
+	}
+	log.Printf("Datastore error for key %s: %s", key, err)
+	writeError(w, http.StatusInternalServerError, codeInternal, "internal server error")
+}
+
+func writeError(w http.ResponseWriter, status int, code, message string) {
+	writeJSON(w, status, ErrorResponse{Code: code, Message: message})
+}
+
+func writeJSON(w http.ResponseWriter, status int, body any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(body); err != nil {
+		log.Printf("Error encoding response: %s", err)
+	}
+}
@@ -0,0 +1,147 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func newTestMux(t *testing.T) *http.ServeMux {
+	t.Helper()
+	mux := http.NewServeMux()
+	mux.Handle(dbPathPrefix, dbHandler(openTestDb(t)))
+	return mux
+}
+
+func doRequest(h http.Handler, method, path, body string) *httptest.ResponseRecorder {
+	var req *http.Request
+	if body == "" {
+		req = httptest.NewRequest(method, path, nil)
+	} else {
+		req = httptest.NewRequest(method, path, strings.NewReader(body))
+	}
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+	return rec
+}
+
+func decodeError(t *testing.T, rec *httptest.ResponseRecorder) ErrorResponse {
+	t.Helper()
+	var e ErrorResponse
+	if err := json.NewDecoder(rec.Body).Decode(&e); err != nil {
+		t.Fatalf("cannot decode error body %q: %s", rec.Body.String(), err)
+	}
+	return e
+}
+
+func TestDbHandler_CRUD(t *testing.T) {
+	h := newTestMux(t)
+
+	rec := doRequest(h, http.MethodPost, "/db/key", `{"value": "v1"}`)
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("create: status %d", rec.Code)
+	}
+	if loc := rec.Header().Get("Location"); loc != "/db/key" {
+		t.Errorf("unexpected Location %q", loc)
+	}
+
+	rec = doRequest(h, http.MethodPut, "/db/key", `{"value": "v2"}`)
+	if rec.Code != http.StatusNoContent {
+		t.Fatalf("update: status %d", rec.Code)
+	}
+
+	rec = doRequest(h, http.MethodGet, "/db/key", "")
+	var resp Response
+	if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil || rec.Code != http.StatusOK {
+		t.Fatalf("get: status %d, %v", rec.Code, err)
+	}
+	if resp.Key != "key" || resp.Value != "v2" {
+		t.Errorf("unexpected response %+v", resp)
+	}
+
+	if rec = doRequest(h, http.MethodDelete, "/db/key", ""); rec.Code != http.StatusNoContent {
+		t.Fatalf("delete: status %d", rec.Code)
+	}
+
+	rec = doRequest(h, http.MethodGet, "/db/key", "")
+	if rec.Code != http.StatusNotFound || decodeError(t, rec).Code != codeNotFound {
+		t.Errorf("get after delete: status %d", rec.Code)
+	}
+	rec = doRequest(h, http.MethodDelete, "/db/key", "")
+	if rec.Code != http.StatusNotFound || decodeError(t, rec).Code != codeNotFound {
+		t.Errorf("repeated delete: status %d", rec.Code)
+	}
+}
+
+func TestDbHandler_PutIfAbsent(t *testing.T) {
+	h := newTestMux(t)
+	put := func(value string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPut, "/db/key", strings.NewReader(`{"value": "`+value+`"}`))
+		req.Header.Set("If-None-Match", "*")
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, req)
+		return rec
+	}
+
+	if rec := put("v1"); rec.Code != http.StatusCreated {
+		t.Fatalf("create: status %d", rec.Code)
+	}
+	rec := put("v2")
+	if rec.Code != http.StatusPreconditionFailed || decodeError(t, rec).Code != codeExists {
+		t.Fatalf("existing key: status %d", rec.Code)
+	}
+
+	rec = doRequest(h, http.MethodGet, "/db/key", "")
+	var resp Response
+	if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil || resp.Value != "v1" {
+		t.Errorf("conditional put overwrote the value: %+v, %v", resp, err)
+	}
+}
+
+func TestDbHandler_KeysWithSlashes(t *testing.T) {
+	h := newTestMux(t)
+
+	if rec := doRequest(h, http.MethodPut, "/db/a/b", `{"value": "nested"}`); rec.Code != http.StatusCreated {
+		t.Fatalf("put: status %d", rec.Code)
+	}
+	if rec := doRequest(h, http.MethodGet, "/db/b", ""); rec.Code != http.StatusNotFound {
+		t.Errorf("key b must not alias a/b, got status %d", rec.Code)
+	}
+
+	rec := doRequest(h, http.MethodGet, "/db/a%2Fb", "")
+	var resp Response
+	if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil || rec.Code != http.StatusOK {
+		t.Fatalf("get escaped: status %d, %v", rec.Code, err)
+	}
+	if resp.Key != "a/b" || resp.Value != "nested" {
+		t.Errorf("unexpected response %+v", resp)
+	}
+}
+
+func TestDbHandler_Errors(t *testing.T) {
+	h := newTestMux(t)
+
+	tests := []struct {
+		name, method, path, body string
+		status                   int
+		code                     string
+	}{
+		{"empty key", http.MethodGet, "/db/", "", http.StatusBadRequest, codeInvalidKey},
+		{"invalid json", http.MethodPost, "/db/k", "{", http.StatusBadRequest, codeInvalidBody},
+		{"missing value", http.MethodPost, "/db/k", `{"other": 1}`, http.StatusBadRequest, codeInvalidBody},
+		{"method", http.MethodPatch, "/db/k", "", http.StatusMethodNotAllowed, codeMethodNotAllowed},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := doRequest(h, tt.method, tt.path, tt.body)
+			if rec.Code != tt.status {
+				t.Fatalf("status %d, expected %d", rec.Code, tt.status)
+			}
+			if e := decodeError(t, rec); e.Code != tt.code || e.Message == "" {
+				t.Errorf("unexpected error body %+v", e)
+			}
+		})
+	}
+}
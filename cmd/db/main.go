@@ -1,12 +1,13 @@
 package main
 
 import (
-	"encoding/json"
 	"flag"
+	"fmt"
 	"log"
+	"net"
 	"net/http"
 	"os"
-	"path/filepath"
+	"time"
 
 	"github.com/roman-mazur/architecture-practice-4-template/datastore"
 	"github.com/roman-mazur/architecture-practice-4-template/httptools"
@@ -14,11 +15,9 @@ import (
 )
 
 var port = flag.Int("port", 8070, "database server port")
+var binaryPort = flag.Int("binary-port", 8071, "binary protocol port, 0 disables it")
 
-type Response struct {
-	Key   string `json:"key"`
-	Value string `json:"value"`
-}
+const shutdownTimeout = 10 * time.Second
 
 func main() {
 	flag.Parse()
@@ -29,57 +28,36 @@ func main() {
 		log.Fatalf("Failed to create DB directory: %s", err)
 	}
 
-	db, err := datastore.Open(dbDir)
+	m := newMetrics()
+	db, err := datastore.Open(dbDir, datastore.WithHooks(m.hooks()))
 	if err != nil {
 		log.Fatalf("Failed to open database: %s", err)
 	}
 	defer db.Close()
 
-	http.HandleFunc("/db/", func(w http.ResponseWriter, r *http.Request) {
-		if r.Method == http.MethodGet {
-			key := filepath.Base(r.URL.Path)
-			
-			value, err := db.Get(key)
-			if err != nil {
-				log.Printf("Error fetching key %s: %s", key, err)
-				http.Error(w, "Not found", http.StatusNotFound)
-				return
-			}
+	http.Handle("/db/", dbHandler(db))
+	http.Handle("/metrics", m.handler(db))
 
-			w.Header().Set("Content-Type", "application/json")
-			w.WriteHeader(http.StatusOK)
-			response := Response{
-				Key:   key,
-				Value: value,
-			}
-			if err := json.NewEncoder(w).Encode(response); err != nil {
-				log.Printf("Error encoding response: %s", err)
-			}
-		} else if r.Method == http.MethodPost {
-			key := filepath.Base(r.URL.Path)
-			
-			var reqBody struct {
-				Value string `json:"value"`
-			}
-			
-			if err := json.NewDecoder(r.Body).Decode(&reqBody); err != nil {
-				http.Error(w, "Invalid request body", http.StatusBadRequest)
-				return
-			}
-			
-			if err := db.Put(key, reqBody.Value); err != nil {
-				log.Printf("Error storing key %s: %s", key, err)
-				http.Error(w, "Internal server error", http.StatusInternalServerError)
-				return
-			}
-			
-			w.WriteHeader(http.StatusOK)
-		} else {
-			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	var binary *binaryServer
+	if *binaryPort > 0 {
+		ln, err := net.Listen("tcp", fmt.Sprintf(":%d", *binaryPort))
+		if err != nil {
+			log.Fatalf("Failed to listen on binary port: %s", err)
 		}
-	})
+		log.Printf("Serving binary protocol at port %d", *binaryPort)
+		binary = newBinaryServer(ln, db)
+		go binary.serve()
+	}
 
 	server := httptools.CreateServer(*port, nil)
 	server.Start()
 	signal.WaitForTerminationSignal()
+	// Сховище закривається відкладеним викликом лише після того, як жоден
+	// обробник бінарних з'єднань уже не пише в нього.
+	if binary != nil {
+		binary.Close()
+	}
+	if err := server.Shutdown(shutdownTimeout); err != nil {
+		log.Printf("Graceful shutdown interrupted: %s", err)
+	}
 }
@@ -0,0 +1,185 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"slices"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/roman-mazur/architecture-practice-4-template/datastore"
+)
+
+// Межі бакетів гістограм у секундах.
+var (
+	opBuckets          = []float64{0.0001, 0.0005, 0.001, 0.005, 0.01, 0.05, 0.1, 0.5, 1}
+	maintenanceBuckets = []float64{0.001, 0.01, 0.1, 0.5, 1, 5, 10, 30, 60}
+)
+
+type counter struct {
+	v atomic.Uint64
+}
+
+func (c *counter) inc() {
+	c.v.Add(1)
+}
+
+type histogram struct {
+	buckets []float64
+
+	mu     sync.Mutex
+	counts []uint64 // counts[i] — спостереження <= buckets[i]; останній — +Inf
+	sum    float64
+	count  uint64
+}
+
+func newHistogram(buckets []float64) *histogram {
+	return &histogram{buckets: buckets, counts: make([]uint64, len(buckets)+1)}
+}
+
+func (h *histogram) observe(d time.Duration) {
+	v := d.Seconds()
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	i := 0
+	for i < len(h.buckets) && v > h.buckets[i] {
+		i++
+	}
+	h.counts[i]++
+	h.sum += v
+	h.count++
+}
+
+// metrics збирає показники datastore і віддає їх у текстовому форматі Prometheus.
+type metrics struct {
+	putDuration, getDuration, deleteDuration *histogram
+	rotationDuration, compactionDuration     *histogram
+
+	putErrors, getErrors, getMisses, deleteErrors counter
+	rotations, rotationErrors                     counter
+	compactions, compactionErrors                 counter
+}
+
+func newMetrics() *metrics {
+	return &metrics{
+		putDuration:        newHistogram(opBuckets),
+		getDuration:        newHistogram(opBuckets),
+		deleteDuration:     newHistogram(opBuckets),
+		rotationDuration:   newHistogram(maintenanceBuckets),
+		compactionDuration: newHistogram(maintenanceBuckets),
+	}
+}
+
+func (m *metrics) hooks() datastore.Hooks {
+	return datastore.Hooks{
+		OnPut: func(d time.Duration, err error) {
+			m.putDuration.observe(d)
+			if err != nil {
+				m.putErrors.inc()
+			}
+		},
+		OnGet: func(d time.Duration, err error) {
+			m.getDuration.observe(d)
+			if errors.Is(err, datastore.ErrNotFound) {
+				m.getMisses.inc()
+			} else if err != nil {
+				m.getErrors.inc()
+			}
+		},
+		OnDelete: func(d time.Duration, err error) {
+			m.deleteDuration.observe(d)
+			if err != nil && !errors.Is(err, datastore.ErrNotFound) {
+				m.deleteErrors.inc()
+			}
+		},
+		OnRotate: func(d time.Duration, err error) {
+			m.rotations.inc()
+			m.rotationDuration.observe(d)
+			if err != nil {
+				m.rotationErrors.inc()
+			}
+		},
+		OnCompact: func(d time.Duration, err error) {
+			m.compactions.inc()
+			m.compactionDuration.observe(d)
+			if err != nil {
+				m.compactionErrors.inc()
+			}
+		},
+	}
+}
+
+func (m *metrics) handler(db *datastore.Db) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			w.Header().Set("Allow", "GET")
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		st, err := db.Stats()
+		if err != nil {
+			log.Printf("Error collecting datastore stats: %s", err)
+			http.Error(w, "Internal server error", http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "text/plain; version=0.0.4")
+		m.write(w, st)
+	}
+}
+
+func (m *metrics) write(w io.Writer, st datastore.Stats) {
+	writeHistogram(w, "datastore_put_duration_seconds", "Latency of Put operations.", m.putDuration)
+	writeHistogram(w, "datastore_get_duration_seconds", "Latency of Get operations.", m.getDuration)
+	writeHistogram(w, "datastore_delete_duration_seconds", "Latency of Delete operations.", m.deleteDuration)
+
+	writeHeader(w, "datastore_errors_total", "counter", "Failed datastore operations.")
+	fmt.Fprintf(w, "datastore_errors_total{op=\"put\"} %d\n", m.putErrors.v.Load())
+	fmt.Fprintf(w, "datastore_errors_total{op=\"get\"} %d\n", m.getErrors.v.Load())
+	fmt.Fprintf(w, "datastore_errors_total{op=\"delete\"} %d\n", m.deleteErrors.v.Load())
+	fmt.Fprintf(w, "datastore_errors_total{op=\"rotate\"} %d\n", m.rotationErrors.v.Load())
+	fmt.Fprintf(w, "datastore_errors_total{op=\"compact\"} %d\n", m.compactionErrors.v.Load())
+	writeSimple(w, "datastore_get_misses_total", "counter", "Get operations for missing keys.", float64(m.getMisses.v.Load()))
+
+	writeSimple(w, "datastore_keys", "gauge", "Number of keys in the index.", float64(st.Keys))
+	writeSimple(w, "datastore_segments", "gauge", "Number of segment files including the active one.", float64(st.Segments))
+	writeSimple(w, "datastore_segments_bytes", "gauge", "Total size of segment files.", float64(st.SegmentBytes))
+
+	writeSimple(w, "datastore_rotations_total", "counter", "Segment rotations.", float64(m.rotations.v.Load()))
+	writeHistogram(w, "datastore_rotation_duration_seconds", "Duration of segment rotations.", m.rotationDuration)
+	writeSimple(w, "datastore_compactions_total", "counter", "Segment compactions.", float64(m.compactions.v.Load()))
+	writeHistogram(w, "datastore_compaction_duration_seconds", "Duration of segment compactions.", m.compactionDuration)
+}
+
+func writeHeader(w io.Writer, name, kind, help string) {
+	fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s %s\n", name, help, name, kind)
+}
+
+func writeSimple(w io.Writer, name, kind, help string, v float64) {
+	writeHeader(w, name, kind, help)
+	fmt.Fprintf(w, "%s %g\n", name, v)
+}
+
+// writeHistogram знімає копію гістограми під h.mu, а пише вже без блокування:
+// observe викликається синхронно з Put і Get, і повільний клієнт /metrics не
+// має їх затримувати.
+func writeHistogram(w io.Writer, name, help string, h *histogram) {
+	h.mu.Lock()
+	counts := slices.Clone(h.counts)
+	sum, count := h.sum, h.count
+	h.mu.Unlock()
+
+	writeHeader(w, name, "histogram", help)
+	var cumulative uint64
+	for i, le := range h.buckets {
+		cumulative += counts[i]
+		fmt.Fprintf(w, "%s_bucket{le=\"%g\"} %d\n", name, le, cumulative)
+	}
+	fmt.Fprintf(w, "%s_bucket{le=\"+Inf\"} %d\n", name, count)
+	fmt.Fprintf(w, "%s_sum %g\n", name, sum)
+	fmt.Fprintf(w, "%s_count %d\n", name, count)
+}
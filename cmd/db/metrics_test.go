@@ -0,0 +1,116 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/roman-mazur/architecture-practice-4-template/datastore"
+)
+
+func TestHistogram(t *testing.T) {
+	h := newHistogram([]float64{0.01, 0.1})
+	h.observe(5 * time.Millisecond)
+	h.observe(50 * time.Millisecond)
+	h.observe(time.Second)
+
+	var sb strings.Builder
+	writeHistogram(&sb, "test_seconds", "Test.", h)
+	out := sb.String()
+	for _, line := range []string{
+		`test_seconds_bucket{le="0.01"} 1`,
+		`test_seconds_bucket{le="0.1"} 2`,
+		`test_seconds_bucket{le="+Inf"} 3`,
+		`test_seconds_count 3`,
+		`# TYPE test_seconds histogram`,
+	} {
+		if !strings.Contains(out, line+"\n") {
+			t.Errorf("missing line %q in:\n%s", line, out)
+		}
+	}
+}
+
+// stalledWriter імітує повільного клієнта: перший Write чекає на release.
+type stalledWriter struct {
+	started chan struct{}
+	release chan struct{}
+}
+
+func (w *stalledWriter) Write(p []byte) (int, error) {
+	select {
+	case <-w.started:
+	default:
+		close(w.started)
+		<-w.release
+	}
+	return len(p), nil
+}
+
+func TestHistogram_SlowWriterDoesNotBlockObserve(t *testing.T) {
+	h := newHistogram([]float64{0.01})
+	w := &stalledWriter{started: make(chan struct{}), release: make(chan struct{})}
+	written := make(chan struct{})
+	go func() {
+		defer close(written)
+		writeHistogram(w, "test_seconds", "Test.", h)
+	}()
+	<-w.started
+
+	observed := make(chan struct{})
+	go func() {
+		defer close(observed)
+		h.observe(time.Millisecond)
+	}()
+	select {
+	case <-observed:
+	case <-time.After(time.Second):
+		t.Error("observe waits for the metrics writer")
+	}
+	close(w.release)
+	<-written
+	<-observed
+}
+
+func TestMetricsHandler(t *testing.T) {
+	m := newMetrics()
+	db, err := datastore.Open(t.TempDir(), datastore.WithHooks(m.hooks()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = db.Close() })
+
+	_ = db.Put("a", "1")
+	_ = db.Put("b", "2")
+	_, _ = db.Get("a")
+	_, _ = db.Get("missing")
+	// PUT через HTTP не читає значення, тож не впливає на метрики Get.
+	put := httptest.NewRecorder()
+	dbHandler(db).ServeHTTP(put, httptest.NewRequest(http.MethodPut, "/db/c", strings.NewReader(`{"value": "3"}`)))
+	if put.Code != http.StatusCreated {
+		t.Fatalf("put status %d", put.Code)
+	}
+	_ = db.Compact()
+
+	rec := httptest.NewRecorder()
+	m.handler(db).ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status %d", rec.Code)
+	}
+	out := rec.Body.String()
+	for _, line := range []string{
+		"datastore_put_duration_seconds_count 3",
+		"datastore_get_duration_seconds_count 2",
+		"datastore_get_misses_total 1",
+		`datastore_errors_total{op="get"} 0`,
+		"datastore_keys 3",
+		"datastore_segments 1",
+		"datastore_compactions_total 1",
+		"datastore_rotations_total 0",
+	} {
+		if !strings.Contains(out, line+"\n") {
+			t.Errorf("missing line %q in:\n%s", line, out)
+		}
+	}
+}
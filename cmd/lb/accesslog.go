@@ -0,0 +1,250 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"math/rand"
+	"net/http"
+	"os"
+	"sync"
+	"time"
+)
+
+const requestIDHeader = "X-Request-Id"
+
+// accessEntry — один запис журналу доступу.
+type accessEntry struct {
+	Time          time.Time `json:"time"`
+	RequestID     string    `json:"requestId"`
+	Client        string    `json:"client"`
+	Method        string    `json:"method"`
+	Path          string    `json:"path"`
+	Backend       string    `json:"backend,omitempty"` // бекенд останньої спроби
+	Status        int       `json:"status"`
+	BytesSent     int64     `json:"bytesSent"`
+	BytesReceived int64     `json:"bytesReceived"`
+	UpstreamMs    float64   `json:"upstreamLatencyMs"` // час до заголовків відповіді бекенда
+	DurationMs    float64   `json:"durationMs"`
+	Retries       int       `json:"retries"`
+}
+
+// accessLogger пише записи журналу доступу у форматі JSON, по одному на рядок.
+type accessLogger struct {
+	mu     sync.Mutex
+	out    io.Writer
+	sample float64 // частка записів, що потрапляють у журнал
+}
+
+var (
+	accessLog     *accessLogger // nil, якщо журнал доступу вимкнено
+	accessLogRoll = rand.Float64
+)
+
+// newAccessLogger відкриває журнал доступу: "-" означає stdout, інакше — файл,
+// що ротується після maxSize байтів.
+func newAccessLogger(path string, sample float64, maxSize int64, backups int) (*accessLogger, error) {
+	if sample < 0 || sample > 1 {
+		return nil, fmt.Errorf("access log sample rate must be between 0 and 1")
+	}
+	if path == "-" {
+		return &accessLogger{out: os.Stdout, sample: sample}, nil
+	}
+	f, err := openRotatingFile(path, maxSize, backups)
+	if err != nil {
+		return nil, err
+	}
+	return &accessLogger{out: f, sample: sample}, nil
+}
+
+// write додає запис у журнал з урахуванням вибірки. Збої бекендів і балансувальника
+// записуються завжди.
+func (l *accessLogger) write(e *accessEntry) {
+	if e.Status < http.StatusInternalServerError && accessLogRoll() >= l.sample {
+		return
+	}
+	line, err := json.Marshal(e)
+	if err != nil {
+		log.Printf("Failed to encode access log entry: %s", err)
+		return
+	}
+	line = append(line, '\n')
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if _, err := l.out.Write(line); err != nil {
+		log.Printf("Failed to write access log: %s", err)
+	}
+}
+
+func (l *accessLogger) close() {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if c, ok := l.out.(io.Closer); ok && l.out != os.Stdout {
+		_ = c.Close()
+	}
+}
+
+// requestID повертає ідентифікатор запиту від клієнта чи попереднього проксі
+// або створює новий і додає його до запиту, щоб бекенд отримав той самий.
+func requestID(r *http.Request) string {
+	if id := r.Header.Get(requestIDHeader); id != "" {
+		return id
+	}
+	id := fmt.Sprintf("%016x", rand.Uint64())
+	r.Header.Set(requestIDHeader, id)
+	return id
+}
+
+// startAccessLog починає запис журналу для запиту. Повернений ResponseWriter
+// запам'ятовує статус і розмір відповіді, які клієнт отримав від балансувальника.
+func startAccessLog(rw http.ResponseWriter, r *http.Request) (*accessEntry, *loggingWriter) {
+	e := &accessEntry{
+		Time:      now(),
+		RequestID: requestID(r),
+		Client:    clientIP(r),
+		Method:    r.Method,
+		Path:      r.URL.Path,
+	}
+	rw.Header().Set(requestIDHeader, e.RequestID)
+	return e, &loggingWriter{ResponseWriter: rw}
+}
+
+// finishAccessLog доповнює запис і додає його в журнал.
+func finishAccessLog(e *accessEntry, lw *loggingWriter) {
+	// Після перемикання протоколу відповідь іде повз loggingWriter, тож статус
+	// і розмір беруться зі статистики пересилання.
+	if lw.status != 0 {
+		e.Status = lw.status
+		e.BytesSent = lw.written
+	}
+	e.DurationMs = millis(now().Sub(e.Time))
+	accessLog.write(e)
+}
+
+// recordAttempt переносить у запис журналу результат спроби до бекенда.
+func (e *accessEntry) recordAttempt(server *BackendServer, st forwardStats, attempt int) {
+	if e == nil {
+		return
+	}
+	e.Backend = server.Address
+	e.Status = st.StatusCode
+	e.BytesSent = st.ResponseBytes
+	e.BytesReceived = st.RequestBytes
+	e.UpstreamMs = millis(st.Latency)
+	e.Retries = attempt
+}
+
+func millis(d time.Duration) float64 {
+	return float64(d) / float64(time.Millisecond)
+}
+
+// loggingWriter запам'ятовує статус і кількість байтів відповіді.
+type loggingWriter struct {
+	http.ResponseWriter
+	status  int
+	written int64
+}
+
+func (w *loggingWriter) WriteHeader(code int) {
+	if w.status == 0 || w.status < http.StatusOK {
+		w.status = code
+	}
+	w.ResponseWriter.WriteHeader(code)
+}
+
+func (w *loggingWriter) Write(p []byte) (int, error) {
+	if w.status == 0 {
+		w.status = http.StatusOK
+	}
+	n, err := w.ResponseWriter.Write(p)
+	w.written += int64(n)
+	return n, err
+}
+
+// Unwrap дає http.ResponseController доступ до Flush і Hijack.
+func (w *loggingWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+// rotatingFile — файл журналу, який після maxSize байтів перейменовується
+// на path.1 (старіші копії зсуваються до path.<backups>), а запис продовжується в новий файл.
+type rotatingFile struct {
+	path    string
+	maxSize int64 // 0 вимикає ротацію
+	backups int
+	f       *os.File
+	size    int64
+}
+
+func openRotatingFile(path string, maxSize int64, backups int) (*rotatingFile, error) {
+	if maxSize < 0 || backups < 0 {
+		return nil, fmt.Errorf("access log size and backups must not be negative")
+	}
+	rf := &rotatingFile{path: path, maxSize: maxSize, backups: backups}
+	if err := rf.open(); err != nil {
+		return nil, err
+	}
+	return rf, nil
+}
+
+func (rf *rotatingFile) open() error {
+	f, err := os.OpenFile(rf.path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+	if err != nil {
+		return err
+	}
+	info, err := f.Stat()
+	if err != nil {
+		_ = f.Close()
+		return err
+	}
+	rf.f, rf.size = f, info.Size()
+	return nil
+}
+
+// Write не синхронізований: його викликає accessLogger під своїм mu.
+func (rf *rotatingFile) Write(p []byte) (int, error) {
+	if rf.maxSize > 0 && rf.size > 0 && rf.size+int64(len(p)) > rf.maxSize {
+		// Якщо ротація не вдалася, запис не губиться, а йде в поточний файл;
+		// ротацію буде повторено на наступному записі.
+		if err := rf.rotate(); err != nil {
+			log.Printf("Failed to rotate access log: %s", err)
+		}
+	}
+	n, err := rf.f.Write(p)
+	rf.size += int64(n)
+	return n, err
+}
+
+// rotate перейменовує файли ще до закриття поточного: якщо перейменування чи
+// відкриття нового файлу не вдалося, запис продовжується в поточний.
+// Без резервних копій файл просто обрізається — він відкритий з O_APPEND.
+func (rf *rotatingFile) rotate() error {
+	if rf.backups == 0 {
+		if err := rf.f.Truncate(0); err != nil {
+			return err
+		}
+		rf.size = 0
+		return nil
+	}
+	for i := rf.backups - 1; i > 0; i-- {
+		err := os.Rename(fmt.Sprintf("%s.%d", rf.path, i), fmt.Sprintf("%s.%d", rf.path, i+1))
+		if err != nil && !os.IsNotExist(err) {
+			return err
+		}
+	}
+	// Файлу може не бути, якщо попередня ротація не змогла відкрити новий.
+	if err := os.Rename(rf.path, rf.path+".1"); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	old := rf.f
+	if err := rf.open(); err != nil {
+		return err
+	}
+	_ = old.Close()
+	return nil
+}
+
+func (rf *rotatingFile) Close() error {
+	return rf.f.Close()
+}
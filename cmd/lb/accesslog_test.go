@@ -0,0 +1,200 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"math/rand"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+// useAccessLog вмикає журнал доступу, що пише в буфер.
+func useAccessLog(t *testing.T, sample float64) *bytes.Buffer {
+	t.Helper()
+	var buf bytes.Buffer
+	accessLog = &accessLogger{out: &buf, sample: sample}
+	t.Cleanup(func() { accessLog = nil })
+	return &buf
+}
+
+func accessEntries(t *testing.T, buf *bytes.Buffer) []accessEntry {
+	t.Helper()
+	var res []accessEntry
+	sc := bufio.NewScanner(buf)
+	for sc.Scan() {
+		var e accessEntry
+		require.NoError(t, json.Unmarshal(sc.Bytes(), &e), sc.Text())
+		res = append(res, e)
+	}
+	return res
+}
+
+func TestAccessLog_Entry(t *testing.T) {
+	var gotID string
+	frontend := useProxyBackend(t, func(rw http.ResponseWriter, r *http.Request) {
+		gotID = r.Header.Get(requestIDHeader)
+		_, _ = rw.Write([]byte("hello"))
+	})
+	buf := useAccessLog(t, 1)
+
+	resp, err := http.Post(frontend.URL+"/api/v1/some-data?key=secret", "text/plain", strings.NewReader("body"))
+	require.NoError(t, err)
+	resp.Body.Close()
+	id := resp.Header.Get(requestIDHeader)
+	require.NotEmpty(t, id)
+	require.Equal(t, id, gotID, "the backend must see the same request ID")
+
+	entries := accessEntries(t, buf)
+	require.Len(t, entries, 1)
+	e := entries[0]
+	require.Equal(t, id, e.RequestID)
+	require.Equal(t, "127.0.0.1", e.Client)
+	require.Equal(t, http.MethodPost, e.Method)
+	require.Equal(t, "/api/v1/some-data", e.Path)
+	require.Contains(t, backendStats, e.Backend)
+	require.Equal(t, http.StatusOK, e.Status)
+	require.EqualValues(t, 5, e.BytesSent)
+	require.EqualValues(t, 4, e.BytesReceived)
+	require.Positive(t, e.UpstreamMs)
+	require.Zero(t, e.Retries)
+	require.WithinDuration(t, time.Now(), e.Time, time.Minute)
+
+	// Ідентифікатор від клієнта передається далі без змін.
+	req, err := http.NewRequest(http.MethodGet, frontend.URL, nil)
+	require.NoError(t, err)
+	req.Header.Set(requestIDHeader, "from-client")
+	resp, err = http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	resp.Body.Close()
+	require.Equal(t, "from-client", gotID)
+	require.Equal(t, "from-client", accessEntries(t, buf)[0].RequestID)
+}
+
+func TestAccessLog_RetriesAndErrors(t *testing.T) {
+	dead, good := newTestBackend(t), newTestBackend(t)
+	useBackends(t, dead, good)
+	dead.Close()
+	preferBackend(t, dead.addr())
+	retries = newRetryBudget(0.2)
+	buf := useAccessLog(t, 1)
+
+	sendRequests(1)
+	e := accessEntries(t, buf)[0]
+	require.Equal(t, good.addr(), e.Backend)
+	require.Equal(t, 1, e.Retries)
+	require.Equal(t, http.StatusOK, e.Status)
+
+	backendStats = make(map[string]*BackendServer)
+	rec := httptest.NewRecorder()
+	handleRequest(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+	e = accessEntries(t, buf)[0]
+	require.Equal(t, http.StatusServiceUnavailable, e.Status)
+	require.Empty(t, e.Backend)
+	require.EqualValues(t, rec.Body.Len(), e.BytesSent)
+}
+
+func TestAccessLog_Sampling(t *testing.T) {
+	good, bad := newTestBackend(t), newTestBackend(t)
+	useBackends(t, good, bad)
+	bad.failing.Store(true)
+	useCircuitBreaker(t, CircuitBreaker{Disabled: true})
+	outlierDetection = OutlierDetection{Disabled: true}
+	t.Cleanup(func() { outlierDetection = OutlierDetection{}.withDefaults() })
+	*maxRetries = 0
+	t.Cleanup(func() { *maxRetries = 2 })
+	buf := useAccessLog(t, 0.25)
+	rolls := []float64{0.1, 0.5, 0.9, 0.2}
+	accessLogRoll = func() float64 {
+		r := rolls[0]
+		rolls = append(rolls[1:], r)
+		return r
+	}
+	t.Cleanup(func() { accessLogRoll = rand.Float64 })
+
+	sendRequests(8)
+	statuses := map[int]int{}
+	for _, e := range accessEntries(t, buf) {
+		statuses[e.Status]++
+	}
+	// Половина успішних запитів потрапляє у вибірку, збої записуються всі.
+	require.Equal(t, map[int]int{http.StatusOK: 2, http.StatusInternalServerError: 4}, statuses)
+}
+
+func TestRotatingFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "access.log")
+	rf, err := openRotatingFile(path, 10, 2)
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = rf.Close() })
+
+	for _, line := range []string{"first\n", "second\n", "third\n", "fourth\n"} {
+		_, err := rf.Write([]byte(line))
+		require.NoError(t, err)
+	}
+	read := func(name string) string {
+		data, err := os.ReadFile(name)
+		require.NoError(t, err)
+		return string(data)
+	}
+	require.Equal(t, "fourth\n", read(path))
+	require.Equal(t, "third\n", read(path+".1"))
+	require.Equal(t, "second\n", read(path+".2"))
+	require.NoFileExists(t, path+".3")
+
+	// Після перезапуску запис продовжується в наявний файл.
+	require.NoError(t, rf.Close())
+	rf, err = openRotatingFile(path, 100, 2)
+	require.NoError(t, err)
+	_, err = rf.Write([]byte("fifth\n"))
+	require.NoError(t, err)
+	require.Equal(t, "fourth\nfifth\n", read(path))
+}
+
+func TestRotatingFile_RenameFails(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "access.log")
+	rf, err := openRotatingFile(path, 10, 1)
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = rf.Close() })
+
+	// Каталог на місці резервної копії не дає перейменувати журнал.
+	require.NoError(t, os.MkdirAll(filepath.Join(path+".1", "busy"), 0o755))
+	_, err = rf.Write([]byte("first\n"))
+	require.NoError(t, err)
+	// Запис не губиться, а йде в поточний файл.
+	_, err = rf.Write([]byte("second\n"))
+	require.NoError(t, err)
+
+	// Наступна ротація вдається.
+	require.NoError(t, os.RemoveAll(path+".1"))
+	_, err = rf.Write([]byte("third\n"))
+	require.NoError(t, err)
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	require.Equal(t, "third\n", string(data))
+	data, err = os.ReadFile(path + ".1")
+	require.NoError(t, err)
+	require.Equal(t, "first\nsecond\n", string(data))
+}
+
+func TestRotatingFile_NoBackups(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "access.log")
+	rf, err := openRotatingFile(path, 10, 0)
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = rf.Close() })
+
+	for _, line := range []string{"first\n", "second\n", "third\n"} {
+		_, err := rf.Write([]byte(line))
+		require.NoError(t, err)
+	}
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	require.Equal(t, "third\n", string(data))
+	require.NoFileExists(t, path+".1")
+}
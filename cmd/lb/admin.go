@@ -0,0 +1,187 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"sort"
+	"time"
+)
+
+type backendView struct {
+	Address     string  `json:"address"`
+	Pool        string  `json:"pool,omitempty"`
+	Weight      int     `json:"weight"`
+	Healthy     bool    `json:"healthy"`
+	Draining    bool    `json:"draining"`
+	Drained     bool    `json:"drained"` // дренування завершено, бекенд можна зупиняти
+	Traffic     int64   `json:"traffic"`
+	ActiveConns int64   `json:"activeConns"`
+	Errors      int64   `json:"errors"`
+	SlowStart   float64 `json:"slowStartFactor"`
+	Override    string  `json:"override,omitempty"`
+
+	BytesSent     int64        `json:"bytesSent"`
+	BytesReceived int64        `json:"bytesReceived"`
+	Latency       *latencyView `json:"latency,omitempty"`
+
+	HealthCheck HealthCheck  `json:"healthCheck"`
+	Transport   Transport    `json:"transport"`
+	Connections poolView     `json:"connections"`
+	Health      healthStatus `json:"health"`
+	Outlier     outlierState `json:"outlier"`
+	Breaker     breakerState `json:"circuitBreaker"`
+
+	samples []time.Duration // знімок затримок для Latency, див. withLatency
+}
+
+// viewOf знімає стан бекенда для API адміністрування. Перцентилі затримки
+// заповнює withLatency вже без mu. Викликається під mu.
+func viewOf(s *BackendServer, t time.Time) backendView {
+	return backendView{
+		Address:       s.Address,
+		Pool:          s.Pool,
+		Weight:        weightOf(s),
+		Healthy:       s.Healthy,
+		Draining:      s.Draining,
+		Drained:       s.Draining && s.ActiveConns == 0,
+		Traffic:       s.Traffic,
+		ActiveConns:   s.ActiveConns,
+		Errors:        s.Errors,
+		SlowStart:     slowStartFactor(s, t),
+		Override:      s.Override,
+		BytesSent:     s.BytesSent,
+		BytesReceived: s.BytesReceived,
+		samples:       s.latency.snapshot(),
+		HealthCheck:   s.HealthCheck,
+		Transport:     s.Transport,
+		Connections:   poolOf(s),
+		Health:        s.health,
+		Outlier:       s.outlier,
+		Breaker:       s.breaker,
+	}
+}
+
+// withLatency рахує перцентилі затримки зі знімка, зробленого viewOf.
+func (v backendView) withLatency() backendView {
+	v.Latency, v.samples = latencyViewOf(v.samples), nil
+	return v
+}
+
+func snapshotBackends() []backendView {
+	mu.Lock()
+	t := now()
+	res := make([]backendView, 0, len(backendStats))
+	for _, s := range backendStats {
+		res = append(res, viewOf(s, t))
+	}
+	mu.Unlock()
+	for i := range res {
+		res[i] = res[i].withLatency()
+	}
+	sort.Slice(res, func(i, j int) bool { return res[i].Address < res[j].Address })
+	return res
+}
+
+// adminHandler — API керування бекендами без перезапуску балансувальника.
+func adminHandler() http.Handler {
+	h := http.NewServeMux()
+
+	h.HandleFunc("GET /backends", func(rw http.ResponseWriter, r *http.Request) {
+		writeJSON(rw, http.StatusOK, snapshotBackends())
+	})
+
+	h.HandleFunc("GET /backends/{addr}", func(rw http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		server, ok := backendStats[r.PathValue("addr")]
+		var view backendView
+		if ok {
+			view = viewOf(server, now())
+		}
+		mu.Unlock()
+		if !ok {
+			writeAdminError(rw, errBackendNotFound)
+			return
+		}
+		writeJSON(rw, http.StatusOK, view.withLatency())
+	})
+
+	h.HandleFunc("GET /backends/{addr}/circuit", func(rw http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		server, ok := backendStats[r.PathValue("addr")]
+		var state breakerState
+		if ok {
+			server.breaker.available(now())
+			state = server.breaker
+		}
+		mu.Unlock()
+		if !ok {
+			writeAdminError(rw, errBackendNotFound)
+			return
+		}
+		writeJSON(rw, http.StatusOK, state)
+	})
+
+	h.HandleFunc("POST /backends", func(rw http.ResponseWriter, r *http.Request) {
+		var cfg BackendConfig
+		if err := json.NewDecoder(r.Body).Decode(&cfg); err != nil {
+			http.Error(rw, "Invalid backend", http.StatusBadRequest)
+			return
+		}
+		if err := addBackend(cfg); err != nil {
+			writeAdminError(rw, err)
+			return
+		}
+		rw.WriteHeader(http.StatusCreated)
+	})
+
+	h.HandleFunc("POST /backends/{addr}/drain", func(rw http.ResponseWriter, r *http.Request) {
+		if err := drainBackend(r.PathValue("addr")); err != nil {
+			writeAdminError(rw, err)
+			return
+		}
+		rw.WriteHeader(http.StatusNoContent)
+	})
+
+	for action, override := range map[string]string{"up": overrideUp, "down": overrideDown, "auto": ""} {
+		h.HandleFunc("POST /backends/{addr}/"+action, func(rw http.ResponseWriter, r *http.Request) {
+			if err := setOverride(r.PathValue("addr"), override); err != nil {
+				writeAdminError(rw, err)
+				return
+			}
+			rw.WriteHeader(http.StatusNoContent)
+		})
+	}
+
+	h.HandleFunc("DELETE /backends/{addr}", func(rw http.ResponseWriter, r *http.Request) {
+		if err := removeBackend(r.PathValue("addr")); err != nil {
+			writeAdminError(rw, err)
+			return
+		}
+		rw.WriteHeader(http.StatusNoContent)
+	})
+
+	return h
+}
+
+func writeAdminError(rw http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, errBackendNotFound):
+		http.Error(rw, err.Error(), http.StatusNotFound)
+	case errors.Is(err, errBackendExists):
+		http.Error(rw, err.Error(), http.StatusConflict)
+	case errors.Is(err, errPoolNotFound), errors.Is(err, errInvalidBackend):
+		http.Error(rw, err.Error(), http.StatusBadRequest)
+	default:
+		http.Error(rw, err.Error(), http.StatusInternalServerError)
+	}
+}
+
+func writeJSON(rw http.ResponseWriter, status int, body any) {
+	rw.Header().Set("content-type", "application/json")
+	rw.WriteHeader(status)
+	if err := json.NewEncoder(rw).Encode(body); err != nil {
+		log.Printf("Failed to write response: %s", err)
+	}
+}
@@ -2,11 +2,15 @@ package main
 
 import (
 	"context"
+	"crypto/tls"
 	"flag"
-	"fmt"
-	"io"
 	"log"
+	"net"
 	"net/http"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
 	"sync"
 	"time"
 
@@ -15,10 +19,37 @@ import (
 )
 
 var (
-	port        = flag.Int("port", 8090, "load balancer port")
-	timeoutSec  = flag.Int("timeout-sec", 3, "request timeout time in seconds")
-	https       = flag.Bool("https", false, "whether backends support HTTPs")
-	traceEnabled = flag.Bool("trace", false, "whether to include tracing information into responses")
+	port             = flag.Int("port", 8090, "load balancer port")
+	timeoutSec       = flag.Int("timeout-sec", 3, "request timeout time in seconds")
+	https            = flag.Bool("https", false, "whether backends support HTTPs")
+	tlsCert          = flag.String("tls-cert", "", "comma-separated certificate files for TLS termination, chosen by SNI; reloaded on SIGHUP")
+	tlsKey           = flag.String("tls-key", "", "comma-separated private key files matching -tls-cert")
+	backendCA        = flag.String("backend-ca", "", "CA certificate file used to verify HTTPS backends")
+	backendCert      = flag.String("backend-cert", "", "client certificate file presented to HTTPS backends; reloaded on SIGHUP")
+	backendKey       = flag.String("backend-key", "", "private key file matching -backend-cert")
+	traceEnabled     = flag.Bool("trace", false, "whether to include tracing information into responses")
+	strategyName     = flag.String("strategy", strategyLeastTraffic, "balancing strategy: "+strings.Join(strategyNames(), ", "))
+	trafficHalfLife  = flag.Duration("traffic-half-life", time.Minute, "half-life of the decaying byte counter used by the least-bytes strategy")
+	configPath       = flag.String("config", "", "path to a JSON file with backends, reloaded on SIGHUP")
+	discoverName     = flag.String("discover", "", "DNS name to resolve into the backend set instead of -config")
+	discoverType     = flag.String("discover-type", discoveryA, "DNS record type used for discovery: a or srv")
+	discoverPort     = flag.Int("discover-port", 8080, "backend port for addresses discovered from A records")
+	discoverInterval = flag.Duration("discover-interval", 10*time.Second, "how often to re-resolve the discovery DNS name")
+	maxRetries       = flag.Int("max-retries", 2, "how many times an idempotent request may be retried on another backend")
+	retryMethodList  = flag.String("retry-methods", "GET,HEAD", "comma-separated HTTP methods that are safe to retry")
+	retryBudgetRatio = flag.Float64("retry-budget", 0.2, "retries allowed per forwarded request on average")
+	retryBodyLimit   = flag.Int64("retry-body-limit", 64<<10, "largest request body buffered for replay on retry")
+	stickySpec       = flag.String("sticky", "", "session affinity: cookie, cookie:<name> or header:<name>; empty disables it")
+	slowStart        = flag.Duration("slow-start", 30*time.Second, "window during which a backend that became healthy ramps up to its full share of traffic, 0 disables it")
+	shutdownTimeout  = flag.Duration("shutdown-timeout", 15*time.Second, "how long to wait for in-flight requests on shutdown")
+	adminPort        = flag.Int("admin-port", 8091, "admin API port, 0 disables it")
+	adminHost        = flag.String("admin-host", "127.0.0.1", "address the admin API listens on; the API has no authentication, so keep it private")
+	hedgePercentile  = flag.Float64("hedge-percentile", 0, "backend latency percentile after which an idempotent GET is also sent to another backend, 0 disables hedging")
+	accessLogPath    = flag.String("access-log", "", "file for JSON access logs, - for stdout; empty disables them")
+	accessLogSample  = flag.Float64("access-log-sample", 1, "fraction of requests written to the access log; failed requests are always logged")
+	accessLogMaxSize = flag.Int64("access-log-max-size", 100<<20, "size in bytes after which the access log file is rotated, 0 disables rotation")
+	accessLogBackups = flag.Int("access-log-backups", 5, "how many rotated access log files to keep")
+	hashOn           = flag.String("hash-on", "ip", "request attribute for the consistent-hash strategy: ip, path, header:<name>, cookie:<name> or query:<name>")
 )
 
 var (
@@ -31,14 +62,40 @@ var (
 )
 
 type BackendServer struct {
-	Address string
-	Traffic int64
-	Healthy bool
+	Address     string
+	Pool        string // назва пулу; порожня для пулу за замовчуванням
+	Traffic     int64
+	Healthy     bool
+	Weight      int   // вага для weighted-round-robin; 0 означає 1
+	ActiveConns int64 // запити, які зараз обробляються бекендом
+
+	BytesSent     int64           // байти відповідей, переданих клієнтам
+	BytesReceived int64           // байти тіл запитів, переданих бекенду
+	RecentBytes   decayingCounter // BytesSent+BytesReceived у згасаючому вікні
+
+	Errors   int64         // збої з'єднання та відповіді 5xx
+	Draining bool          // нові запити не надсилаються, поточні завершуються
+	Override string        // ручний стан з API адміністрування: overrideUp, overrideDown або порожній
+	stop     chan struct{} // закривається при видаленні бекенда
+
+	HealthCheck HealthCheck
+	Transport   Transport
+	client      *http.Client // пул з'єднань з бекендом, див. setTransport
+	probeClient *http.Client // окремі з'єднання для активних перевірок
+	pool        *connPool
+	health      healthStatus
+	outlier     outlierState
+	breaker     breakerState
+	latency     latencyWindow
+	hedge       hedgeEstimate
+
+	healthySince time.Time // початок розігріву після переходу у здоровий стан
 }
 
 var (
 	backendStats = make(map[string]*BackendServer)
 	mu           sync.Mutex
+	strategy     Strategy = leastTraffic{}
 )
 
 func scheme() string {
@@ -48,113 +105,295 @@ func scheme() string {
 	return "http"
 }
 
-func health(dst string) bool {
-	ctx, cancel := context.WithTimeout(context.Background(), timeout)
-	defer cancel()
+// healthyServers повертає здорові бекенди пулу в стабільному порядку. Викликається під mu.
+func healthyServers(pool string) []*BackendServer {
+	t := now()
+	var res []*BackendServer
+	for _, server := range backendStats {
+		if server.Pool != pool {
+			continue
+		}
+		if !inRotation(server) || server.outlier.ejected(t) || !server.breaker.available(t) {
+			continue
+		}
+		// Вилучення скінчилося, а розігрів після нього ще не починався.
+		if until := server.outlier.EjectedUntil; !until.IsZero() && server.healthySince.Before(until) {
+			warmUp(server, t)
+		}
+		res = append(res, server)
+	}
+	sort.Slice(res, func(i, j int) bool { return res[i].Address < res[j].Address })
+	return res
+}
+
+// frontendIdleTimeout — скільки клієнтське keep-alive з'єднання може простоювати між запитами.
+const frontendIdleTimeout = 90 * time.Second
 
-	req, _ := http.NewRequestWithContext(ctx, "GET",
-		fmt.Sprintf("%s://%s/health", scheme(), dst), nil)
-	resp, err := http.DefaultClient.Do(req)
-	if err != nil {
-		return false
+// newFrontend створює сервер, що приймає запити клієнтів. Загальні ліміти
+// на читання й запис обірвали б потокові тіла й довгі маршрути, тож тривалість
+// запиту обмежують лише таймаути маршрутів і дедлайни в контексті.
+func newFrontend(port int, tlsConfig *tls.Config) httptools.Server {
+	opts := []httptools.Option{
+		httptools.WithTimeouts(0, 0),
+		httptools.WithIdleTimeout(frontendIdleTimeout),
 	}
-	defer resp.Body.Close()
+	if tlsConfig != nil {
+		return httptools.CreateTLSServer(port, http.HandlerFunc(handleRequest), tlsConfig, opts...)
+	}
+	return httptools.CreateServer(port, http.HandlerFunc(handleRequest), opts...)
+}
 
-	return resp.StatusCode == http.StatusOK
+// inRotation повідомляє, чи бекенд має отримувати запити з огляду на активні
+// перевірки, ручний стан і дренування. Викликається під mu.
+func inRotation(server *BackendServer) bool {
+	healthy := server.Healthy && server.Override != overrideDown || server.Override == overrideUp
+	return healthy && !server.Draining
 }
 
-func forward(dst string, rw http.ResponseWriter, r *http.Request) error {
-	ctx, cancel := context.WithTimeout(r.Context(), timeout)
-	defer cancel()
+// inFlight повертає кількість запитів, які зараз пересилаються бекендам.
+func inFlight() int64 {
+	mu.Lock()
+	defer mu.Unlock()
+	var n int64
+	for _, server := range backendStats {
+		n += server.ActiveConns
+	}
+	return n
+}
 
-	fwdRequest := r.Clone(ctx)
-	fwdRequest.RequestURI = ""
-	fwdRequest.URL.Host = dst
-	fwdRequest.URL.Scheme = scheme()
-	fwdRequest.Host = dst
+func getLeastTrafficServer() *BackendServer {
+	mu.Lock()
+	defer mu.Unlock()
+	return leastTraffic{}.Choose(nil, healthyServers(""))
+}
 
-	resp, err := http.DefaultClient.Do(fwdRequest)
-	if err == nil {
-		defer resp.Body.Close()
-		for k, values := range resp.Header {
-			for _, value := range values {
-				rw.Header().Add(k, value)
-			}
+// chooseServer обирає закріплений за клієнтом бекенд або бекенд за стратегією пулу,
+// до якого веде маршрут запиту, пропускаючи вже випробувані, й одразу враховує новий активний запит.
+// Якщо всі бекенди зайняті, запит чекає в черзі; busy повідомляє, що місце так і не звільнилося.
+func chooseServer(r *http.Request, tried map[string]bool) (server *BackendServer, busy bool) {
+	return pickServer(r, tried, true)
+}
+
+func pickServer(r *http.Request, tried map[string]bool, queue bool) (*BackendServer, bool) {
+	mu.Lock()
+	defer mu.Unlock()
+	pool := routeFor(r)
+	var w backendWait
+	defer w.done()
+	var candidates []*BackendServer
+	for {
+		candidates = healthyServers(pool)
+		if len(tried) > 0 {
+			candidates = slices.DeleteFunc(candidates, func(s *BackendServer) bool { return tried[s.Address] })
 		}
-		if *traceEnabled {
-			rw.Header().Set("lb-from", dst)
+		if len(candidates) == 0 {
+			return nil, false
 		}
-		log.Println("fwd", resp.StatusCode, resp.Request.URL)
-		rw.WriteHeader(resp.StatusCode)
-		_, err := io.Copy(rw, resp.Body)
-		if err != nil {
-			log.Printf("Failed to write response: %s", err)
+		if available := slices.DeleteFunc(slices.Clone(candidates), saturated); len(available) > 0 {
+			candidates = available
+			break
+		}
+		if !queue || !w.wait(r) {
+			return nil, true
 		}
-		return nil
-	} else {
-		log.Printf("Failed to get response from %s: %s", dst, err)
-		rw.WriteHeader(http.StatusServiceUnavailable)
-		return err
 	}
+	var server *BackendServer
+	if sticky != nil {
+		server = sticky.server(r, candidates)
+	}
+	if server == nil {
+		server = applySlowStart(r, strategyFor(pool), candidates, now())
+	}
+	if server != nil {
+		server.ActiveConns++
+		server.breaker.acquire()
+	}
+	return server, false
 }
 
-func getLeastTrafficServer() *BackendServer {
+// finishAttempt звільняє бекенд після спроби й враховує її результат.
+// Спроби, перервані клієнтом, не вважаються збоями бекенда.
+func finishAttempt(r *http.Request, server *BackendServer, st forwardStats, err error) {
 	mu.Lock()
 	defer mu.Unlock()
+	server.ActiveConns--
+	backendFreed.Broadcast()
+	recordTraffic(server, st)
+	if clientCanceled(r, err) {
+		server.breaker.release()
+		return
+	}
+	recordResult(server, st, err)
+	trackOutcome(server, st, err)
+	trackBreaker(server, st, err)
+	if err == nil {
+		server.Traffic++
+	}
+}
 
-	var selected *BackendServer
-	for _, server := range backendStats {
-		if server.Healthy {
-			if selected == nil || server.Traffic < selected.Traffic {
-				selected = server
+func handleRequest(rw http.ResponseWriter, r *http.Request) {
+	var entry *accessEntry
+	if accessLog != nil {
+		var lw *loggingWriter
+		entry, lw = startAccessLog(rw, r)
+		defer finishAccessLog(entry, lw)
+		rw = lw
+	}
+
+	release, ok := admit(rw, r)
+	if !ok {
+		return
+	}
+	defer release()
+
+	// Тайм-аут охоплює читання тіла для повторів і всі спроби. Для перемикання
+	// протоколу forward обмежує лише рукостискання.
+	if upgradeType(r.Header) == "" {
+		mu.Lock()
+		d := timeoutFor(r)
+		mu.Unlock()
+		ctx, cancel := context.WithTimeout(r.Context(), d)
+		defer cancel()
+		r = r.WithContext(ctx)
+	}
+
+	body, retryable := readRetryBody(rw, r)
+	retries.deposit()
+
+	tried := make(map[string]bool)
+	var lastErr error
+	for attempt := 0; ; attempt++ {
+		server, busy := chooseServer(r, tried)
+		if server == nil && lastErr != nil {
+			rw.WriteHeader(errorStatus(lastErr))
+			return
+		}
+		if busy {
+			mu.Lock()
+			wait := limits.cfg.queueTimeout()
+			mu.Unlock()
+			writeRetryAfter(rw, wait)
+			http.Error(rw, "Load balancer is overloaded", http.StatusServiceUnavailable)
+			return
+		}
+		if server == nil {
+			http.Error(rw, "No healthy servers available", http.StatusServiceUnavailable)
+			return
+		}
+		tried[server.Address] = true
+		if *traceEnabled && attempt > 0 {
+			rw.Header().Set("lb-retries", strconv.Itoa(attempt))
+		}
+		var st forwardStats
+		var err error
+		if delay, ok := hedgeDelay(r, server); ok {
+			server, st, err = forwardHedged(rw, r, server, tried, delay)
+		} else {
+			if sticky != nil {
+				sticky.pin(rw, r, server)
 			}
+			mu.Lock()
+			client := server.httpClient()
+			mu.Unlock()
+			st, err = forward(client, server.Address, rw, r)
+			finishAttempt(r, server, st, err)
 		}
+		entry.recordAttempt(server, st, attempt)
+
+		if err == nil {
+			return
+		}
+		lastErr = err
+		if !retryable || attempt >= *maxRetries || r.Context().Err() != nil || !retries.withdraw() {
+			rw.WriteHeader(errorStatus(err))
+			return
+		}
+		log.Printf("Retrying %s %s after failure on %s", r.Method, r.URL, server.Address)
+		rewindBody(r, body)
 	}
-	return selected
 }
 
 func main() {
 	flag.Parse()
 	timeout = time.Duration(*timeoutSec) * time.Second
 
-	for _, addr := range serversPool {
-		backendStats[addr] = &BackendServer{
-			Address: addr,
-			Traffic: 0,
-			Healthy: false,
+	var err error
+	if strategy, err = newStrategy(*strategyName, *hashOn); err != nil {
+		log.Fatalf("Invalid balancing strategy: %s", err)
+	}
+	if sticky, err = parseSticky(*stickySpec); err != nil {
+		log.Fatalf("Invalid session affinity: %s", err)
+	}
+	if *accessLogPath != "" {
+		if accessLog, err = newAccessLogger(*accessLogPath, *accessLogSample, *accessLogMaxSize, *accessLogBackups); err != nil {
+			log.Fatalf("Invalid access log settings: %s", err)
 		}
 	}
+	retries = newRetryBudget(*retryBudgetRatio)
+	retryMethods = parseMethods(*retryMethodList)
 
-	for _, addr := range serversPool {
-		addr := addr
-		go func() {
-			for range time.Tick(5 * time.Second) {
-				isHealthy := health(addr)
-				mu.Lock()
-				backendStats[addr].Healthy = isHealthy
-				mu.Unlock()
-				log.Println(addr, "healthy:", isHealthy)
-			}
-		}()
+	// Налаштування TLS потрібні до створення пулів з'єднань з бекендами.
+	var backendCerts *certStore
+	if backendTLS, backendCerts, err = newBackendTLS(*backendCA, *backendCert, *backendKey); err != nil {
+		log.Fatalf("Invalid backend TLS settings: %s", err)
 	}
-
-	frontend := httptools.CreateServer(*port, http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
-		server := getLeastTrafficServer()
-		if server == nil {
-			http.Error(rw, "No healthy servers available", http.StatusServiceUnavailable)
-			return
+	frontendConfig, frontendCerts, err := frontendTLS(*tlsCert, *tlsKey)
+	if err != nil {
+		log.Fatalf("Invalid TLS settings: %s", err)
+	}
+	for _, store := range []*certStore{frontendCerts, backendCerts} {
+		if store != nil {
+			signal.OnReloadSignal(func() {
+				if err := store.reload(); err != nil {
+					log.Printf("Certificate reload failed, keeping current ones: %s", err)
+				}
+			})
 		}
+	}
 
-		err := forward(server.Address, rw, r)
-		if err == nil {
-			mu.Lock()
-			server.Traffic++
-			mu.Unlock()
+	switch {
+	case *discoverName != "":
+		d, err := newDiscovery(net.DefaultResolver, *discoverName, *discoverType, *discoverPort)
+		if err != nil {
+			log.Fatalf("Invalid discovery settings: %s", err)
+		}
+		log.Printf("Discovering backends from %s records of %s", *discoverType, *discoverName)
+		go d.run(*discoverInterval)
+	case *configPath != "":
+		cfg, err := loadConfig(*configPath)
+		if err != nil {
+			log.Fatalf("Failed to load config: %s", err)
 		}
-	}))
+		applyConfig(cfg)
+		signal.OnReloadSignal(func() { reloadConfig(*configPath) })
+	default:
+		applyConfig(defaultConfig())
+	}
+
+	var admin httptools.Server
+	if *adminPort > 0 {
+		admin = httptools.CreateServer(*adminPort, adminHandler(), httptools.WithHost(*adminHost))
+		admin.Start()
+	}
+
+	frontend := newFrontend(*port, frontendConfig)
 
 	log.Println("Starting load balancer...")
+	log.Printf("Balancing strategy: %s", *strategyName)
 	log.Printf("Tracing support enabled: %t", *traceEnabled)
+	log.Printf("TLS termination enabled: %t", frontendConfig != nil)
 	frontend.Start()
 	signal.WaitForTerminationSignal()
+
+	// Нові з'єднання більше не приймаються, запити, що вже пересилаються, завершуються.
+	log.Printf("Waiting up to %s for %d in-flight requests", *shutdownTimeout, inFlight())
+	if err := frontend.Shutdown(*shutdownTimeout); err != nil {
+		log.Printf("Graceful shutdown interrupted: %s", err)
+	}
+	if admin != nil {
+		_ = admin.Shutdown(time.Second)
+	}
+	if accessLog != nil {
+		accessLog.close()
+	}
 }
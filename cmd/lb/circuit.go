@@ -0,0 +1,194 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"time"
+)
+
+const (
+	defaultBreakerFailures = 5
+	defaultSlowThreshold   = 2 * time.Second
+	defaultOpenTimeout     = 10 * time.Second
+	defaultHalfOpenTrials  = 3
+)
+
+// CircuitBreaker — налаштування запобіжника бекенда. Помилкою вважається збій
+// з'єднання, відповідь 5xx або відповідь, повільніша за SlowThreshold.
+type CircuitBreaker struct {
+	Disabled         bool     `json:"disabled,omitempty"`
+	FailureThreshold int      `json:"failureThreshold,omitempty"` // помилок поспіль, щоб розімкнути
+	SlowThreshold    Duration `json:"slowThreshold,omitempty"`    // час до заголовків відповіді, після якого запит вважається невдалим
+	OpenTimeout      Duration `json:"openTimeout,omitempty"`      // скільки тримати розімкнутим до пробних запитів
+	HalfOpenTrials   int      `json:"halfOpenTrials,omitempty"`   // успішних пробних запитів, щоб замкнути
+}
+
+var circuitBreaker = CircuitBreaker{}.withDefaults()
+
+func (cb CircuitBreaker) withDefaults() CircuitBreaker {
+	if cb.FailureThreshold <= 0 {
+		cb.FailureThreshold = defaultBreakerFailures
+	}
+	if cb.SlowThreshold <= 0 {
+		cb.SlowThreshold = Duration(defaultSlowThreshold)
+	}
+	if cb.OpenTimeout <= 0 {
+		cb.OpenTimeout = Duration(defaultOpenTimeout)
+	}
+	if cb.HalfOpenTrials <= 0 {
+		cb.HalfOpenTrials = defaultHalfOpenTrials
+	}
+	return cb
+}
+
+func (cb *CircuitBreaker) validate() error {
+	if cb == nil {
+		return nil
+	}
+	if cb.FailureThreshold < 0 || cb.SlowThreshold < 0 || cb.OpenTimeout < 0 || cb.HalfOpenTrials < 0 {
+		return fmt.Errorf("circuit breaker settings must not be negative")
+	}
+	return nil
+}
+
+func resolveCircuitBreaker(cb *CircuitBreaker) CircuitBreaker {
+	if cb == nil {
+		return CircuitBreaker{}.withDefaults()
+	}
+	return cb.withDefaults()
+}
+
+type circuitState int
+
+const (
+	circuitClosed circuitState = iota
+	circuitOpen
+	circuitHalfOpen
+)
+
+func (s circuitState) String() string {
+	switch s {
+	case circuitOpen:
+		return "open"
+	case circuitHalfOpen:
+		return "half-open"
+	default:
+		return "closed"
+	}
+}
+
+func (s circuitState) MarshalText() ([]byte, error) {
+	return []byte(s.String()), nil
+}
+
+func (s *circuitState) UnmarshalText(text []byte) error {
+	switch string(text) {
+	case "closed":
+		*s = circuitClosed
+	case "open":
+		*s = circuitOpen
+	case "half-open":
+		*s = circuitHalfOpen
+	default:
+		return fmt.Errorf("unknown circuit state %q", text)
+	}
+	return nil
+}
+
+// breakerState — стан запобіжника бекенда.
+type breakerState struct {
+	State    circuitState `json:"state"`
+	Failures int          `json:"consecutiveFailures"`
+	OpenedAt time.Time    `json:"openedAt,omitzero"`
+
+	trials    int // пробні запити, що зараз виконуються
+	successes int // успішні пробні запити
+}
+
+// available повідомляє, чи можна надіслати бекенду запит. Розімкнутий запобіжник
+// після OpenTimeout переходить у напіврозімкнутий стан. Викликається під mu.
+func (b *breakerState) available(t time.Time) bool {
+	cb := circuitBreaker
+	if cb.Disabled {
+		return true
+	}
+	if b.State == circuitOpen && !t.Before(b.OpenedAt.Add(time.Duration(cb.OpenTimeout))) {
+		b.State = circuitHalfOpen
+		b.trials, b.successes = 0, 0
+	}
+	switch b.State {
+	case circuitOpen:
+		return false
+	case circuitHalfOpen:
+		return b.trials+b.successes < cb.HalfOpenTrials
+	default:
+		return true
+	}
+}
+
+// acquire враховує запит, надісланий бекенду. Викликається під mu.
+func (b *breakerState) acquire() {
+	if b.State == circuitHalfOpen {
+		b.trials++
+	}
+}
+
+// release повертає пробний запит, результат якого не враховується. Викликається під mu.
+func (b *breakerState) release() {
+	if b.State == circuitHalfOpen && b.trials > 0 {
+		b.trials--
+	}
+}
+
+// record враховує результат запиту й повертає true, якщо стан змінився. Викликається під mu.
+func (b *breakerState) record(failed bool, latency time.Duration, t time.Time) bool {
+	cb := circuitBreaker
+	if cb.Disabled {
+		return false
+	}
+	failed = failed || latency >= time.Duration(cb.SlowThreshold)
+
+	switch b.State {
+	case circuitClosed:
+		if !failed {
+			b.Failures = 0
+			return false
+		}
+		b.Failures++
+		if b.Failures < cb.FailureThreshold {
+			return false
+		}
+	case circuitHalfOpen:
+		if b.trials > 0 {
+			b.trials--
+		}
+		if !failed {
+			b.successes++
+			if b.successes < cb.HalfOpenTrials {
+				return false
+			}
+			*b = breakerState{State: circuitClosed}
+			return true
+		}
+	default:
+		// Запит почався до розмикання — його результат уже нічого не змінює.
+		return false
+	}
+
+	b.State = circuitOpen
+	b.OpenedAt = t
+	b.trials, b.successes = 0, 0
+	return true
+}
+
+// trackBreaker — record з логуванням для обробника запитів. Викликається під mu.
+func trackBreaker(server *BackendServer, st forwardStats, err error) {
+	b := &server.breaker
+	t := now()
+	if b.record(isFailure(st.StatusCode, err), st.Latency, t) {
+		log.Printf("Circuit breaker of %s is %s", server.Address, b.State)
+		if b.State == circuitClosed {
+			warmUp(server, t)
+		}
+	}
+}
@@ -0,0 +1,122 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func useCircuitBreaker(t *testing.T, cb CircuitBreaker) {
+	t.Helper()
+	circuitBreaker = cb.withDefaults()
+	t.Cleanup(func() { circuitBreaker = CircuitBreaker{}.withDefaults() })
+}
+
+func TestCircuitBreaker_Lifecycle(t *testing.T) {
+	advance := setNow(t)
+	useCircuitBreaker(t, CircuitBreaker{
+		FailureThreshold: 3,
+		OpenTimeout:      Duration(10 * time.Second),
+		HalfOpenTrials:   2,
+	})
+	var b breakerState
+
+	// Успіх скидає лічильник помилок поспіль.
+	b.record(true, 0, now())
+	b.record(true, 0, now())
+	b.record(false, 0, now())
+	require.Equal(t, circuitClosed, b.State)
+
+	for i := 0; i < 2; i++ {
+		require.False(t, b.record(true, 0, now()))
+	}
+	require.True(t, b.record(true, 0, now()))
+	require.Equal(t, circuitOpen, b.State)
+	require.False(t, b.available(now()))
+
+	advance(10 * time.Second)
+	require.True(t, b.available(now()))
+	require.Equal(t, circuitHalfOpen, b.State)
+
+	// У напіврозімкнутому стані пропускається лише HalfOpenTrials запитів.
+	b.acquire()
+	require.True(t, b.available(now()))
+	b.acquire()
+	require.False(t, b.available(now()))
+
+	require.False(t, b.record(false, 0, now()))
+	require.False(t, b.available(now()), "a finished trial must not free a slot")
+	require.True(t, b.record(false, 0, now()))
+	require.Equal(t, breakerState{State: circuitClosed}, b)
+}
+
+func TestCircuitBreaker_FailedTrialReopens(t *testing.T) {
+	advance := setNow(t)
+	useCircuitBreaker(t, CircuitBreaker{FailureThreshold: 1, OpenTimeout: Duration(time.Second)})
+	var b breakerState
+
+	b.record(true, 0, now())
+	advance(time.Second)
+	require.True(t, b.available(now()))
+	b.acquire()
+	require.True(t, b.record(true, 0, now()))
+	require.Equal(t, circuitOpen, b.State)
+	require.Equal(t, now(), b.OpenedAt)
+	require.False(t, b.available(now()))
+}
+
+func TestCircuitBreaker_SlowResponsesCountAsFailures(t *testing.T) {
+	setNow(t)
+	useCircuitBreaker(t, CircuitBreaker{FailureThreshold: 2, SlowThreshold: Duration(time.Second)})
+	var b breakerState
+
+	b.record(false, 999*time.Millisecond, now())
+	require.Zero(t, b.Failures)
+	b.record(false, time.Second, now())
+	b.record(false, 5*time.Second, now())
+	require.Equal(t, circuitOpen, b.State)
+}
+
+func TestCircuitBreaker_Disabled(t *testing.T) {
+	setNow(t)
+	useCircuitBreaker(t, CircuitBreaker{Disabled: true, FailureThreshold: 1})
+	var b breakerState
+
+	require.False(t, b.record(true, 0, now()))
+	require.True(t, b.available(now()))
+}
+
+func TestCircuitBreaker_OpenBackendGetsNoTraffic(t *testing.T) {
+	setNow(t)
+	useCircuitBreaker(t, CircuitBreaker{FailureThreshold: 1})
+	outlierDetection = OutlierDetection{Disabled: true}
+	t.Cleanup(func() { outlierDetection = OutlierDetection{}.withDefaults() })
+
+	good, bad := newTestBackend(t), newTestBackend(t)
+	useBackends(t, good, bad)
+	bad.failing.Store(true)
+
+	sendRequests(2)
+	require.EqualValues(t, 1, bad.hits.Load())
+	require.Equal(t, circuitOpen, backendStats[bad.addr()].breaker.State)
+	for _, status := range sendRequests(4) {
+		require.Equal(t, http.StatusOK, status)
+	}
+	require.EqualValues(t, 1, bad.hits.Load())
+
+	rec := httptest.NewRecorder()
+	adminHandler().ServeHTTP(rec, httptest.NewRequest("GET", "/backends/"+bad.addr()+"/circuit", nil))
+	require.Equal(t, http.StatusOK, rec.Code)
+	var state breakerState
+	require.NoError(t, json.NewDecoder(rec.Body).Decode(&state))
+	require.Equal(t, circuitOpen, state.State)
+	require.Equal(t, 1, state.Failures)
+
+	rec = httptest.NewRecorder()
+	adminHandler().ServeHTTP(rec, httptest.NewRequest("GET", "/backends/missing:80/circuit", nil))
+	require.Equal(t, http.StatusNotFound, rec.Code)
+}
@@ -0,0 +1,93 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// resolver — підмножина *net.Resolver, потрібна для discovery; у тестах підміняється.
+type resolver interface {
+	LookupHost(ctx context.Context, host string) ([]string, error)
+	LookupSRV(ctx context.Context, service, proto, name string) (string, []*net.SRV, error)
+}
+
+const (
+	discoveryA   = "a"
+	discoverySRV = "srv"
+)
+
+var errNoRecords = errors.New("DNS answer contains no records")
+
+// discovery періодично перетворює DNS-ім'я на набір бекендів.
+type discovery struct {
+	resolver resolver
+	name     string
+	kind     string // discoveryA або discoverySRV
+	port     int    // порт для A-записів; SRV містять власний
+}
+
+func newDiscovery(r resolver, name, kind string, port int) (*discovery, error) {
+	if kind != discoveryA && kind != discoverySRV {
+		return nil, fmt.Errorf("unknown discovery record type %q", kind)
+	}
+	return &discovery{resolver: r, name: name, kind: kind, port: port}, nil
+}
+
+func (d *discovery) resolve(ctx context.Context) ([]BackendConfig, error) {
+	var res []BackendConfig
+	if d.kind == discoverySRV {
+		_, records, err := d.resolver.LookupSRV(ctx, "", "", d.name)
+		if err != nil {
+			return nil, err
+		}
+		for _, srv := range records {
+			host := strings.TrimSuffix(srv.Target, ".")
+			res = append(res, BackendConfig{
+				Address: net.JoinHostPort(host, strconv.Itoa(int(srv.Port))),
+				Weight:  int(srv.Weight),
+			})
+		}
+	} else {
+		hosts, err := d.resolver.LookupHost(ctx, d.name)
+		if err != nil {
+			return nil, err
+		}
+		for _, h := range hosts {
+			res = append(res, BackendConfig{Address: net.JoinHostPort(h, strconv.Itoa(d.port))})
+		}
+	}
+	sort.Slice(res, func(i, j int) bool { return res[i].Address < res[j].Address })
+	return res, nil
+}
+
+// refresh оновлює набір бекендів, не чіпаючи інших налаштувань. Якщо DNS
+// недоступний або не повернув жодного запису, лишається останній відомий набір.
+func (d *discovery) refresh(ctx context.Context) error {
+	backends, err := d.resolve(ctx)
+	if err != nil {
+		return err
+	}
+	if len(backends) == 0 {
+		return errNoRecords
+	}
+	applyBackends(backends)
+	return nil
+}
+
+func (d *discovery) run(interval time.Duration) {
+	for {
+		ctx, cancel := context.WithTimeout(context.Background(), interval)
+		if err := d.refresh(ctx); err != nil {
+			log.Printf("Discovery of %s failed, keeping current backends: %s", d.name, err)
+		}
+		cancel()
+		time.Sleep(interval)
+	}
+}
@@ -0,0 +1,111 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+type fakeResolver struct {
+	hosts map[string][]string
+	srv   map[string][]*net.SRV
+	err   error
+}
+
+func (r *fakeResolver) LookupHost(_ context.Context, host string) ([]string, error) {
+	if r.err != nil {
+		return nil, r.err
+	}
+	return r.hosts[host], nil
+}
+
+func (r *fakeResolver) LookupSRV(_ context.Context, _, _, name string) (string, []*net.SRV, error) {
+	if r.err != nil {
+		return "", nil, r.err
+	}
+	return name, r.srv[name], nil
+}
+
+func TestDiscovery_ARecords(t *testing.T) {
+	resetBackends(t)
+	r := &fakeResolver{hosts: map[string][]string{"server": {"10.0.0.2", "10.0.0.1"}}}
+	d, err := newDiscovery(r, "server", discoveryA, 8080)
+	require.NoError(t, err)
+
+	require.NoError(t, d.refresh(context.Background()))
+	require.Len(t, backendStats, 2)
+	require.Contains(t, backendStats, "10.0.0.1:8080")
+	old := backendStats["10.0.0.2:8080"]
+
+	// Одна адреса зникла, одна з'явилась.
+	r.hosts["server"] = []string{"10.0.0.1", "10.0.0.3"}
+	require.NoError(t, d.refresh(context.Background()))
+	require.Len(t, backendStats, 2)
+	require.Contains(t, backendStats, "10.0.0.3:8080")
+	require.NotContains(t, backendStats, "10.0.0.2:8080")
+	require.True(t, isStopped(old))
+	require.False(t, isStopped(backendStats["10.0.0.3:8080"]))
+}
+
+func TestDiscovery_SRVRecords(t *testing.T) {
+	resetBackends(t)
+	r := &fakeResolver{srv: map[string][]*net.SRV{
+		"_http._tcp.server": {
+			{Target: "server1.", Port: 8080, Weight: 3},
+			{Target: "server2.", Port: 8081, Weight: 1},
+		},
+	}}
+	d, err := newDiscovery(r, "_http._tcp.server", discoverySRV, 0)
+	require.NoError(t, err)
+
+	require.NoError(t, d.refresh(context.Background()))
+	require.Len(t, backendStats, 2)
+	require.Equal(t, 3, backendStats["server1:8080"].Weight)
+	require.Equal(t, 1, backendStats["server2:8081"].Weight)
+}
+
+func TestDiscovery_KeepsBackendsOnError(t *testing.T) {
+	resetBackends(t)
+	r := &fakeResolver{hosts: map[string][]string{"server": {"10.0.0.1"}}}
+	d, err := newDiscovery(r, "server", discoveryA, 8080)
+	require.NoError(t, err)
+	require.NoError(t, d.refresh(context.Background()))
+
+	r.err = errors.New("temporary failure")
+	require.Error(t, d.refresh(context.Background()))
+	require.Contains(t, backendStats, "10.0.0.1:8080")
+
+	r.err = nil
+	r.hosts["server"] = nil
+	require.ErrorIs(t, d.refresh(context.Background()), errNoRecords)
+	require.Contains(t, backendStats, "10.0.0.1:8080")
+}
+
+func TestDiscovery_KeepsOtherSettings(t *testing.T) {
+	resetBackends(t)
+	applyConfig(Config{
+		Timeouts: []RouteTimeout{{PathPrefix: "/slow", Timeout: Duration(time.Minute)}},
+		Limits:   &Limits{MaxInFlight: 10},
+	})
+	t.Cleanup(func() { applyConfig(Config{}) })
+
+	r := &fakeResolver{hosts: map[string][]string{"server": {"10.0.0.1"}}}
+	d, err := newDiscovery(r, "server", discoveryA, 8080)
+	require.NoError(t, err)
+	require.NoError(t, d.refresh(context.Background()))
+
+	mu.Lock()
+	defer mu.Unlock()
+	require.Contains(t, backendStats, "10.0.0.1:8080")
+	require.Len(t, routeTimeouts, 1)
+	require.Equal(t, 10, limits.cfg.MaxInFlight)
+}
+
+func TestNewDiscovery_InvalidType(t *testing.T) {
+	_, err := newDiscovery(&fakeResolver{}, "server", "mx", 0)
+	require.Error(t, err)
+}
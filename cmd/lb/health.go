@@ -0,0 +1,235 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+)
+
+const (
+	defaultHealthPath     = "/health"
+	defaultHealthInterval = 5 * time.Second
+	defaultHealthRise     = 2
+	defaultHealthFall     = 3
+	maxHealthBody         = 64 << 10
+)
+
+// Duration — time.Duration, що в JSON записується рядком на кшталт "5s".
+type Duration time.Duration
+
+func (d Duration) MarshalJSON() ([]byte, error) {
+	return json.Marshal(time.Duration(d).String())
+}
+
+func (d *Duration) UnmarshalJSON(data []byte) error {
+	var s string
+	if err := json.Unmarshal(data, &s); err != nil {
+		return fmt.Errorf("duration must be a string like \"5s\": %w", err)
+	}
+	v, err := time.ParseDuration(s)
+	if err != nil {
+		return err
+	}
+	*d = Duration(v)
+	return nil
+}
+
+// HealthCheck — параметри активної перевірки бекенда. Нульові поля
+// успадковуються від загальних налаштувань або значень за замовчуванням.
+type HealthCheck struct {
+	Path           string   `json:"path,omitempty"`
+	Interval       Duration `json:"interval,omitempty"`
+	Timeout        Duration `json:"timeout,omitempty"`
+	ExpectedStatus []int    `json:"expectedStatus,omitempty"`
+	ExpectedBody   string   `json:"expectedBody,omitempty"` // підрядок, який має бути в тілі
+	Rise           int      `json:"rise,omitempty"`         // успіхів поспіль, щоб стати здоровим
+	Fall           int      `json:"fall,omitempty"`         // невдач поспіль, щоб стати нездоровим
+}
+
+// defaultHealthCheck — загальні налаштування з файлу конфігурації.
+var defaultHealthCheck HealthCheck
+
+// merge повертає копію hc, у якій задані поля override мають пріоритет.
+func (hc HealthCheck) merge(override *HealthCheck) HealthCheck {
+	if override == nil {
+		return hc
+	}
+	if override.Path != "" {
+		hc.Path = override.Path
+	}
+	if override.Interval > 0 {
+		hc.Interval = override.Interval
+	}
+	if override.Timeout > 0 {
+		hc.Timeout = override.Timeout
+	}
+	if len(override.ExpectedStatus) > 0 {
+		hc.ExpectedStatus = override.ExpectedStatus
+	}
+	if override.ExpectedBody != "" {
+		hc.ExpectedBody = override.ExpectedBody
+	}
+	if override.Rise > 0 {
+		hc.Rise = override.Rise
+	}
+	if override.Fall > 0 {
+		hc.Fall = override.Fall
+	}
+	return hc
+}
+
+func (hc *HealthCheck) validate() error {
+	if hc == nil {
+		return nil
+	}
+	if hc.Path != "" && !strings.HasPrefix(hc.Path, "/") {
+		return fmt.Errorf("health check path %q must start with /", hc.Path)
+	}
+	if hc.Interval < 0 || hc.Timeout < 0 || hc.Rise < 0 || hc.Fall < 0 {
+		return fmt.Errorf("health check interval, timeout, rise and fall must not be negative")
+	}
+	for _, code := range hc.ExpectedStatus {
+		if code < 100 || code > 599 {
+			return fmt.Errorf("invalid expected status %d", code)
+		}
+	}
+	return nil
+}
+
+func (hc HealthCheck) withDefaults() HealthCheck {
+	return HealthCheck{
+		Path:           defaultHealthPath,
+		Interval:       Duration(defaultHealthInterval),
+		Timeout:        Duration(timeout),
+		ExpectedStatus: []int{http.StatusOK},
+		Rise:           defaultHealthRise,
+		Fall:           defaultHealthFall,
+	}.merge(&hc)
+}
+
+// resolveHealthCheck накладає overrides (налаштування пулу, потім бекенда) на загальні налаштування.
+func resolveHealthCheck(overrides ...*HealthCheck) HealthCheck {
+	hc := defaultHealthCheck
+	for _, o := range overrides {
+		hc = hc.merge(o)
+	}
+	return hc.withDefaults()
+}
+
+// healthStatus — результати активних перевірок бекенда.
+type healthStatus struct {
+	Checked        bool      `json:"checked"`
+	Successes      int       `json:"consecutiveSuccesses"`
+	Failures       int       `json:"consecutiveFailures"`
+	LastCheck      time.Time `json:"lastCheck,omitzero"`
+	LastTransition time.Time `json:"lastTransition,omitzero"`
+	LastError      string    `json:"lastError,omitempty"`
+}
+
+// probe виконує одну активну перевірку бекенда клієнтом client — зазвичай
+// окремим клієнтом перевірок (див. healthClient), а не пулом для запитів.
+func probe(client *http.Client, dst string, hc HealthCheck) error {
+	ctx := context.Background()
+	if hc.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, time.Duration(hc.Timeout))
+		defer cancel()
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "GET",
+		fmt.Sprintf("%s://%s%s", scheme(), dst, hc.Path), nil)
+	if err != nil {
+		return err
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if !slices.Contains(hc.ExpectedStatus, resp.StatusCode) {
+		return fmt.Errorf("unexpected status %d", resp.StatusCode)
+	}
+	if hc.ExpectedBody != "" {
+		body, err := io.ReadAll(io.LimitReader(resp.Body, maxHealthBody))
+		if err != nil {
+			return err
+		}
+		if !strings.Contains(string(body), hc.ExpectedBody) {
+			return fmt.Errorf("body does not contain %q", hc.ExpectedBody)
+		}
+	}
+	return nil
+}
+
+// recordProbe застосовує результат перевірки з урахуванням порогів rise/fall
+// і повідомляє, чи змінився стан. До першої перевірки стан невідомий,
+// тож перший результат застосовується одразу. Викликається під mu.
+func recordProbe(server *BackendServer, err error, t time.Time) bool {
+	h := &server.health
+	first := !h.Checked
+	h.Checked = true
+	h.LastCheck = t
+
+	wasHealthy := server.Healthy
+	if err == nil {
+		h.Successes++
+		h.Failures = 0
+		h.LastError = ""
+		if first || h.Successes >= server.HealthCheck.Rise {
+			server.Healthy = true
+		}
+	} else {
+		h.Failures++
+		h.Successes = 0
+		h.LastError = err.Error()
+		if first || h.Failures >= server.HealthCheck.Fall {
+			server.Healthy = false
+		}
+	}
+
+	if server.Healthy == wasHealthy {
+		return false
+	}
+	h.LastTransition = t
+	return true
+}
+
+func healthLoop(server *BackendServer) {
+	for {
+		mu.Lock()
+		hc := server.HealthCheck
+		client := server.healthClient()
+		mu.Unlock()
+
+		timer := time.NewTimer(time.Duration(hc.Interval))
+		select {
+		case <-server.stop:
+			timer.Stop()
+			return
+		case <-timer.C:
+		}
+
+		err := probe(client, server.Address, hc)
+		mu.Lock()
+		t := now()
+		changed := recordProbe(server, err, t)
+		healthy := server.Healthy
+		if changed && healthy {
+			warmUp(server, t)
+		}
+		mu.Unlock()
+
+		if changed && healthy {
+			log.Printf("Backend %s is healthy", server.Address)
+		} else if changed {
+			log.Printf("Backend %s is unhealthy: %s", server.Address, err)
+		}
+	}
+}
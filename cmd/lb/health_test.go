@@ -0,0 +1,115 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestProbe(t *testing.T) {
+	status, body := http.StatusOK, "OK"
+	backend := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/ready" {
+			rw.WriteHeader(http.StatusNotFound)
+			return
+		}
+		rw.WriteHeader(status)
+		_, _ = rw.Write([]byte(body))
+	}))
+	t.Cleanup(backend.Close)
+	addr := strings.TrimPrefix(backend.URL, "http://")
+
+	hc := HealthCheck{Path: "/ready", Timeout: Duration(time.Second)}.withDefaults()
+	require.NoError(t, probe(http.DefaultClient, addr, hc))
+
+	status = http.StatusNoContent
+	require.Error(t, probe(http.DefaultClient, addr, hc))
+	hc.ExpectedStatus = []int{http.StatusOK, http.StatusNoContent}
+	require.NoError(t, probe(http.DefaultClient, addr, hc))
+
+	status = http.StatusOK
+	hc.ExpectedBody = "ready"
+	require.Error(t, probe(http.DefaultClient, addr, hc))
+	body = "all systems ready"
+	require.NoError(t, probe(http.DefaultClient, addr, hc))
+
+	hc.Path = "/health"
+	require.Error(t, probe(http.DefaultClient, addr, hc))
+}
+
+func TestRecordProbe_RiseFall(t *testing.T) {
+	server := &BackendServer{Address: "srv", HealthCheck: HealthCheck{Rise: 2, Fall: 3}.withDefaults()}
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	fail := errors.New("connection refused")
+
+	// Перший результат застосовується одразу.
+	require.True(t, recordProbe(server, nil, start))
+	require.True(t, server.Healthy)
+
+	// Поодинокі збої не виводять бекенд з ротації.
+	require.False(t, recordProbe(server, fail, start.Add(time.Second)))
+	require.False(t, recordProbe(server, fail, start.Add(2*time.Second)))
+	require.True(t, server.Healthy)
+	require.False(t, recordProbe(server, nil, start.Add(3*time.Second)))
+	require.False(t, recordProbe(server, fail, start.Add(4*time.Second)))
+	require.False(t, recordProbe(server, fail, start.Add(5*time.Second)))
+	require.True(t, recordProbe(server, fail, start.Add(6*time.Second)))
+	require.False(t, server.Healthy)
+	require.Equal(t, start.Add(6*time.Second), server.health.LastTransition)
+	require.Equal(t, "connection refused", server.health.LastError)
+	require.Equal(t, 3, server.health.Failures)
+
+	// Для повернення потрібно rise успіхів поспіль.
+	require.False(t, recordProbe(server, nil, start.Add(7*time.Second)))
+	require.False(t, server.Healthy)
+	require.True(t, recordProbe(server, nil, start.Add(8*time.Second)))
+	require.True(t, server.Healthy)
+	require.Empty(t, server.health.LastError)
+}
+
+func TestRecordProbe_FirstFailure(t *testing.T) {
+	server := &BackendServer{Address: "srv", HealthCheck: HealthCheck{}.withDefaults()}
+	require.False(t, recordProbe(server, errors.New("down"), time.Now()))
+	require.False(t, server.Healthy)
+	require.True(t, server.health.Checked)
+}
+
+func TestResolveHealthCheck(t *testing.T) {
+	timeout = 3 * time.Second
+	defaultHealthCheck = HealthCheck{Path: "/status", Fall: 5}
+	t.Cleanup(func() { defaultHealthCheck = HealthCheck{} })
+
+	hc := resolveHealthCheck(&HealthCheck{Interval: Duration(time.Second), Fall: 1})
+	require.Equal(t, "/status", hc.Path)
+	require.Equal(t, Duration(time.Second), hc.Interval)
+	require.Equal(t, Duration(3*time.Second), hc.Timeout)
+	require.Equal(t, []int{http.StatusOK}, hc.ExpectedStatus)
+	require.Equal(t, defaultHealthRise, hc.Rise)
+	require.Equal(t, 1, hc.Fall)
+}
+
+func TestHealthCheck_JSON(t *testing.T) {
+	var cfg Config
+	err := json.Unmarshal([]byte(`{
+		"healthCheck": {"interval": "2s", "rise": 3},
+		"backends": [{"address": "a:80", "healthCheck": {"path": "/ready", "expectedStatus": [200, 204]}}]
+	}`), &cfg)
+	require.NoError(t, err)
+	require.NoError(t, cfg.validate())
+	require.Equal(t, Duration(2*time.Second), cfg.HealthCheck.Interval)
+	require.Equal(t, "/ready", cfg.Backends[0].HealthCheck.Path)
+
+	data, err := json.Marshal(HealthCheck{Interval: Duration(1500 * time.Millisecond)})
+	require.NoError(t, err)
+	require.JSONEq(t, `{"interval": "1.5s"}`, string(data))
+
+	require.Error(t, json.Unmarshal([]byte(`{"interval": 5}`), &HealthCheck{}))
+	require.Error(t, (&HealthCheck{Path: "health"}).validate())
+	require.Error(t, (&HealthCheck{ExpectedStatus: []int{42}}).validate())
+}
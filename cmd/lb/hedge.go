@@ -0,0 +1,148 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"time"
+)
+
+const (
+	minHedgeSamples     = 20 // скільки відповідей бекенда потрібно, щоб перцентиль затримки мав сенс
+	hedgeRefreshSamples = 64 // через скільки нових відповідей затримка хеджування перераховується
+)
+
+// hedgeEstimate — затримка хеджування бекенда, обчислена за вибіркою latency.
+// Кешується, щоб не сортувати вибірку під mu на кожен GET.
+type hedgeEstimate struct {
+	delay     time.Duration
+	ready     bool
+	total     int64 // latencyWindow.total на момент обчислення
+	computing bool  // перерахунок уже виконує інший запит
+}
+
+// hedgeDelay повертає, скільки чекати на відповідь server, перш ніж надіслати
+// той самий запит ще одному бекенду. Хеджуються лише GET-запити без тіла.
+func hedgeDelay(r *http.Request, server *BackendServer) (time.Duration, bool) {
+	if *hedgePercentile <= 0 || r.Method != http.MethodGet || upgradeType(r.Header) != "" {
+		return 0, false
+	}
+	if r.Body != nil && r.Body != http.NoBody {
+		return 0, false
+	}
+	mu.Lock()
+	w, e := &server.latency, &server.hedge
+	if len(w.samples) < minHedgeSamples {
+		mu.Unlock()
+		return 0, false
+	}
+	var samples []time.Duration
+	if !e.computing && (!e.ready || w.total-e.total >= hedgeRefreshSamples) {
+		e.computing, e.total = true, w.total
+		samples = w.snapshot()
+	}
+	delay, ready := e.delay, e.ready
+	mu.Unlock()
+	if samples == nil {
+		return delay, ready
+	}
+
+	// Сортування — поза mu; поки воно триває, інші запити беруть попередню оцінку.
+	delay = percentilesOf(samples, *hedgePercentile)[0]
+	mu.Lock()
+	e.delay, e.ready, e.computing = delay, true, false
+	mu.Unlock()
+	return delay, true
+}
+
+// hedgeAttempt — один із паралельних запитів до різних бекендів.
+type hedgeAttempt struct {
+	server *BackendServer
+	resp   *http.Response
+	st     forwardStats
+	err    error
+	cancel context.CancelFunc
+}
+
+// forwardHedged надсилає запит бекенду first, а якщо той не відповів за delay —
+// ще й іншому бекенду. Клієнт отримує першу відповідь, інший запит скасовується.
+// Хеджування, як і повтори, витрачає бюджет повторів. Повертає бекенд, чию
+// відповідь передано клієнту, або останній, що не відповів.
+func forwardHedged(rw http.ResponseWriter, r *http.Request, first *BackendServer, tried map[string]bool, delay time.Duration) (*BackendServer, forwardStats, error) {
+	results := make(chan hedgeAttempt, 2)
+	running := make(map[*BackendServer]context.CancelFunc, 2)
+	start := func(server *BackendServer) {
+		mu.Lock()
+		client := server.httpClient()
+		mu.Unlock()
+		ctx, cancel := context.WithCancel(r.Context())
+		running[server] = cancel
+		go func() {
+			resp, st, err := send(ctx, client, server.Address, r)
+			results <- hedgeAttempt{server: server, resp: resp, st: st, err: err, cancel: cancel}
+		}()
+	}
+	start(first)
+
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+	hedge := timer.C
+	var last hedgeAttempt
+	for len(running) > 0 {
+		select {
+		case <-hedge:
+			hedge = nil
+			second, _ := pickServer(r, tried, false)
+			if second == nil {
+				continue
+			}
+			if !retries.withdraw() {
+				releaseServer(second)
+				continue
+			}
+			tried[second.Address] = true
+			start(second)
+		case res := <-results:
+			delete(running, res.server)
+			if res.err != nil {
+				res.cancel()
+				finishAttempt(r, res.server, res.st, res.err)
+				last = res
+				continue
+			}
+			// Перша відповідь виграє; бекенд, що запізнився, лише звільняє місце.
+			hedge = nil
+			for _, cancel := range running {
+				cancel()
+				go abandonAttempt(results)
+			}
+			defer res.cancel()
+			if sticky != nil {
+				sticky.pin(rw, r, res.server)
+			}
+			st, err := respond(res.server.Address, rw, r, res.resp, res.st)
+			finishAttempt(r, res.server, st, err)
+			return res.server, st, err
+		}
+	}
+	return last.server, last.st, last.err
+}
+
+// abandonAttempt чекає на скасований запит і звільняє бекенд, не враховуючи
+// результат у статистиці помилок і затримок.
+func abandonAttempt(results <-chan hedgeAttempt) {
+	res := <-results
+	if res.resp != nil {
+		_ = res.resp.Body.Close()
+	}
+	res.cancel()
+	releaseServer(res.server)
+}
+
+// releaseServer звільняє обраний бекенд, якщо запит до нього скасовано або так і не надіслано.
+func releaseServer(server *BackendServer) {
+	mu.Lock()
+	defer mu.Unlock()
+	server.ActiveConns--
+	server.breaker.release()
+	backendFreed.Broadcast()
+}
@@ -0,0 +1,176 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+// useHedging вмикає хеджування й заповнює вікна затримок бекендів значенням latency.
+func useHedging(t *testing.T, percentile float64, latency time.Duration) {
+	t.Helper()
+	*hedgePercentile = percentile
+	retries = newRetryBudget(0.2)
+	t.Cleanup(func() { *hedgePercentile = 0 })
+	for _, server := range backendStats {
+		for i := 0; i < minHedgeSamples; i++ {
+			server.latency.add(latency)
+		}
+	}
+}
+
+// preferBackend змушує стратегію обирати addr, поки він серед кандидатів.
+func preferBackend(t *testing.T, addr string) {
+	t.Helper()
+	strategy = strategyFunc(func(_ *http.Request, candidates []*BackendServer) *BackendServer {
+		for _, s := range candidates {
+			if s.Address == addr {
+				return s
+			}
+		}
+		return candidates[0]
+	})
+}
+
+func TestHedge_SlowBackend(t *testing.T) {
+	cancelled := make(chan struct{})
+	slow := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		<-r.Context().Done()
+		close(cancelled)
+	}))
+	t.Cleanup(slow.Close)
+	fast := newTestBackend(t)
+	useBackends(t, fast)
+	slowAddr := slow.Listener.Addr().String()
+	backendStats[slowAddr] = &BackendServer{Address: slowAddr, Healthy: true}
+	preferBackend(t, slowAddr)
+	useHedging(t, 90, 10*time.Millisecond)
+	*traceEnabled = true
+	t.Cleanup(func() { *traceEnabled = false })
+
+	rec := httptest.NewRecorder()
+	handleRequest(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+	require.Equal(t, http.StatusOK, rec.Code)
+	require.Equal(t, "ok", rec.Body.String())
+	require.Equal(t, fast.addr(), rec.Header().Get("lb-from"))
+
+	select {
+	case <-cancelled:
+	case <-time.After(time.Second):
+		t.Fatal("the slow request must be cancelled")
+	}
+	require.Eventually(t, func() bool {
+		mu.Lock()
+		defer mu.Unlock()
+		return backendStats[slowAddr].ActiveConns == 0
+	}, time.Second, time.Millisecond)
+	mu.Lock()
+	defer mu.Unlock()
+	require.Zero(t, backendStats[slowAddr].Errors, "a cancelled hedge is not a backend failure")
+	require.Zero(t, backendStats[fast.addr()].ActiveConns)
+	require.EqualValues(t, 1, backendStats[fast.addr()].Traffic)
+}
+
+func TestHedge_FastBackendIsNotHedged(t *testing.T) {
+	first, second := newTestBackend(t), newTestBackend(t)
+	useBackends(t, first, second)
+	preferBackend(t, first.addr())
+	useHedging(t, 90, time.Second)
+
+	for _, status := range sendRequests(3) {
+		require.Equal(t, http.StatusOK, status)
+	}
+	require.EqualValues(t, 3, first.hits.Load())
+	require.Zero(t, second.hits.Load())
+}
+
+func TestHedge_FailureWaitsForHedge(t *testing.T) {
+	release := make(chan struct{})
+	slow := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		<-release
+		_, _ = rw.Write([]byte("late"))
+	}))
+	t.Cleanup(slow.Close)
+	t.Cleanup(func() { close(release) })
+	dead := newTestBackend(t)
+	useBackends(t, dead)
+	dead.Close()
+	slowAddr := slow.Listener.Addr().String()
+	backendStats[slowAddr] = &BackendServer{Address: slowAddr, Healthy: true}
+	preferBackend(t, slowAddr)
+	useHedging(t, 50, time.Millisecond)
+	*maxRetries = 0
+	t.Cleanup(func() { *maxRetries = 2 })
+
+	// Хедж на мертвий бекенд не вдається, тож клієнт чекає на повільний.
+	done := make(chan *httptest.ResponseRecorder)
+	go func() {
+		rec := httptest.NewRecorder()
+		handleRequest(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+		done <- rec
+	}()
+	require.Eventually(t, func() bool {
+		mu.Lock()
+		defer mu.Unlock()
+		return backendStats[dead.addr()].Errors == 1
+	}, time.Second, time.Millisecond)
+	release <- struct{}{}
+	rec := <-done
+	require.Equal(t, http.StatusOK, rec.Code)
+	require.Equal(t, "late", rec.Body.String())
+}
+
+func TestHedgeDelay(t *testing.T) {
+	server := &BackendServer{}
+	get := httptest.NewRequest(http.MethodGet, "/", nil)
+	_, ok := hedgeDelay(get, server)
+	require.False(t, ok, "hedging is disabled by default")
+
+	*hedgePercentile = 90
+	t.Cleanup(func() { *hedgePercentile = 0 })
+	_, ok = hedgeDelay(get, server)
+	require.False(t, ok, "not enough samples")
+
+	for i := 1; i <= 100; i++ {
+		server.latency.add(time.Duration(i) * time.Millisecond)
+	}
+	delay, ok := hedgeDelay(get, server)
+	require.True(t, ok)
+	require.Equal(t, 91*time.Millisecond, delay)
+
+	_, ok = hedgeDelay(httptest.NewRequest(http.MethodPost, "/", nil), server)
+	require.False(t, ok)
+	_, ok = hedgeDelay(httptest.NewRequest(http.MethodGet, "/", strings.NewReader("x")), server)
+	require.False(t, ok)
+	upgrade := httptest.NewRequest(http.MethodGet, "/", nil)
+	upgrade.Header.Set("Connection", "Upgrade")
+	upgrade.Header.Set("Upgrade", "websocket")
+	_, ok = hedgeDelay(upgrade, server)
+	require.False(t, ok)
+}
+
+func TestHedgeDelay_Cached(t *testing.T) {
+	server := &BackendServer{Address: "a"}
+	backendStats = map[string]*BackendServer{"a": server}
+	useHedging(t, 90, 10*time.Millisecond)
+	get := httptest.NewRequest(http.MethodGet, "/", nil)
+
+	delay, ok := hedgeDelay(get, server)
+	require.True(t, ok)
+	require.Equal(t, 10*time.Millisecond, delay)
+
+	// Оцінка не перераховується на кожен запит, лише після hedgeRefreshSamples нових відповідей.
+	for i := 0; i < hedgeRefreshSamples-1; i++ {
+		server.latency.add(time.Second)
+	}
+	delay, _ = hedgeDelay(get, server)
+	require.Equal(t, 10*time.Millisecond, delay)
+
+	server.latency.add(time.Second)
+	delay, _ = hedgeDelay(get, server)
+	require.Equal(t, time.Second, delay)
+}
@@ -0,0 +1,68 @@
+package main
+
+import (
+	"slices"
+	"time"
+)
+
+const latencySamples = 1024
+
+// latencyWindow зберігає затримки останніх latencySamples відповідей бекенда.
+type latencyWindow struct {
+	samples []time.Duration
+	pos     int
+	total   int64 // усі додані відповіді, зокрема витіснені з вибірки
+}
+
+func (w *latencyWindow) add(d time.Duration) {
+	w.total++
+	if len(w.samples) < latencySamples {
+		w.samples = append(w.samples, d)
+		return
+	}
+	w.samples[w.pos] = d
+	w.pos = (w.pos + 1) % latencySamples
+}
+
+// snapshot копіює вибірку, щоб сортувати її вже без mu. Викликається під mu.
+func (w *latencyWindow) snapshot() []time.Duration {
+	return slices.Clone(w.samples)
+}
+
+// percentilesOf сортує непорожню вибірку sorted на місці й повертає затримки для перцентилів ps.
+func percentilesOf(sorted []time.Duration, ps ...float64) []time.Duration {
+	slices.Sort(sorted)
+	res := make([]time.Duration, len(ps))
+	for i, p := range ps {
+		idx := int(p / 100 * float64(len(sorted)))
+		res[i] = sorted[min(max(idx, 0), len(sorted)-1)]
+	}
+	return res
+}
+
+// latencyView — перцентилі затримки для API адміністрування.
+type latencyView struct {
+	P50 Duration `json:"p50"`
+	P90 Duration `json:"p90"`
+	P99 Duration `json:"p99"`
+}
+
+// latencyViewOf сортує знімок вибірки на місці й повертає його перцентилі або nil,
+// якщо відповідей ще не було.
+func latencyViewOf(samples []time.Duration) *latencyView {
+	if len(samples) == 0 {
+		return nil
+	}
+	p := percentilesOf(samples, 50, 90, 99)
+	return &latencyView{P50: Duration(p[0]), P90: Duration(p[1]), P99: Duration(p[2])}
+}
+
+// recordResult оновлює лічильник помилок і затримки бекенда після forward. Викликається під mu.
+func recordResult(server *BackendServer, st forwardStats, err error) {
+	if isFailure(st.StatusCode, err) {
+		server.Errors++
+	}
+	if err == nil {
+		server.latency.add(st.Latency)
+	}
+}
@@ -0,0 +1,29 @@
+package main
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestLatencyWindow(t *testing.T) {
+	var w latencyWindow
+	require.Nil(t, latencyViewOf(w.snapshot()))
+
+	for i := 1; i <= 100; i++ {
+		w.add(time.Duration(i) * time.Millisecond)
+	}
+	require.Equal(t, &latencyView{
+		P50: Duration(51 * time.Millisecond),
+		P90: Duration(91 * time.Millisecond),
+		P99: Duration(100 * time.Millisecond),
+	}, latencyViewOf(w.snapshot()))
+
+	// Старі значення витісняються новими.
+	for i := 0; i < latencySamples; i++ {
+		w.add(time.Second)
+	}
+	require.Len(t, w.samples, latencySamples)
+	require.Equal(t, Duration(time.Second), latencyViewOf(w.snapshot()).P50)
+}
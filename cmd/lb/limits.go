@@ -0,0 +1,330 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"net/http"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	defaultQueueTimeout = time.Second
+	maxTrackedClients   = 10000 // після цього з пам'яті прибираються повні відра неактивних клієнтів
+	clientsAfterEvict   = maxTrackedClients * 9 / 10
+)
+
+// Bucket — параметри token bucket: Rate запитів за секунду з можливим сплеском до Burst.
+type Bucket struct {
+	Rate  float64 `json:"rate"`
+	Burst int     `json:"burst,omitempty"` // за замовчуванням дорівнює Rate, але не менше 1
+}
+
+func (b Bucket) validate() error {
+	if b.Rate <= 0 || b.Burst < 0 {
+		return fmt.Errorf("rate limit must have a positive rate and non-negative burst")
+	}
+	return nil
+}
+
+// RouteRateLimit обмежує загальну частоту запитів до маршруту.
+// Якщо підходить кілька правил, діє правило з найдовшим префіксом.
+type RouteRateLimit struct {
+	PathPrefix string   `json:"pathPrefix"`
+	Methods    []string `json:"methods,omitempty"`
+	Bucket
+}
+
+// Limits захищає бекенди від перевантаження. Нульові поля вимикають відповідне обмеження.
+type Limits struct {
+	PerIP                 *Bucket          `json:"perIP,omitempty"`
+	Routes                []RouteRateLimit `json:"routes,omitempty"`
+	MaxInFlight           int              `json:"maxInFlight,omitempty"`           // запитів, що одночасно обробляє балансувальник
+	MaxInFlightPerBackend int              `json:"maxInFlightPerBackend,omitempty"` // запитів, що одночасно обробляє один бекенд
+	QueueSize             int              `json:"queueSize,omitempty"`             // запитів, що можуть чекати на вільне місце
+	QueueTimeout          Duration         `json:"queueTimeout,omitempty"`
+}
+
+func (l *Limits) validate() error {
+	if l == nil {
+		return nil
+	}
+	if l.PerIP != nil {
+		if err := l.PerIP.validate(); err != nil {
+			return err
+		}
+	}
+	for _, rl := range l.Routes {
+		if !strings.HasPrefix(rl.PathPrefix, "/") {
+			return fmt.Errorf("rate limit path prefix %q must start with /", rl.PathPrefix)
+		}
+		if err := rl.Bucket.validate(); err != nil {
+			return fmt.Errorf("%s: %w", rl.PathPrefix, err)
+		}
+	}
+	if l.MaxInFlight < 0 || l.MaxInFlightPerBackend < 0 || l.QueueSize < 0 || l.QueueTimeout < 0 {
+		return fmt.Errorf("in-flight limits and queue settings must not be negative")
+	}
+	return nil
+}
+
+// tokenBucket поповнюється з часом згідно з now, тож тести керують ним підміною годинника.
+type tokenBucket struct {
+	rate, burst float64
+	tokens      float64
+	updated     time.Time
+}
+
+func newTokenBucket(b Bucket, t time.Time) *tokenBucket {
+	burst := float64(b.Burst)
+	if burst == 0 {
+		burst = max(math.Ceil(b.Rate), 1)
+	}
+	return &tokenBucket{rate: b.Rate, burst: burst, tokens: burst, updated: t}
+}
+
+func (b *tokenBucket) refill(t time.Time) {
+	if dt := t.Sub(b.updated); dt > 0 {
+		b.tokens = min(b.burst, b.tokens+dt.Seconds()*b.rate)
+	}
+	b.updated = t
+}
+
+// take забирає токен або повертає, скільки чекати на наступний.
+func (b *tokenBucket) take(t time.Time) (bool, time.Duration) {
+	b.refill(t)
+	if b.tokens >= 1 {
+		b.tokens--
+		return true, 0
+	}
+	return false, time.Duration((1 - b.tokens) / b.rate * float64(time.Second))
+}
+
+// limiter — стан обмежень для поточних Limits; замінюється при перезавантаженні конфігурації.
+type limiter struct {
+	cfg Limits
+
+	mu      sync.Mutex
+	clients map[string]*tokenBucket
+	routes  []*tokenBucket
+	queued  int
+
+	slots chan struct{} // семафор MaxInFlight; nil — без обмеження
+}
+
+func (l Limits) queueTimeout() time.Duration {
+	if l.QueueTimeout <= 0 {
+		return defaultQueueTimeout
+	}
+	return time.Duration(l.QueueTimeout)
+}
+
+func newLimiter(cfg Limits) *limiter {
+	l := &limiter{cfg: cfg, clients: make(map[string]*tokenBucket)}
+	t := now()
+	for _, rl := range cfg.Routes {
+		l.routes = append(l.routes, newTokenBucket(rl.Bucket, t))
+	}
+	if cfg.MaxInFlight > 0 {
+		l.slots = make(chan struct{}, cfg.MaxInFlight)
+	}
+	return l
+}
+
+// limits захищено mu.
+var limits = newLimiter(Limits{})
+
+// applyLimits замінює обмеження, якщо вони змінилися; інакше стан відер зберігається. Викликається під mu.
+func applyLimits(cfg *Limits) {
+	var next Limits
+	if cfg != nil {
+		next = *cfg
+	}
+	if !reflect.DeepEqual(limits.cfg, next) {
+		limits = newLimiter(next)
+	}
+}
+
+// admit застосовує обмеження частоти й кількості одночасних запитів.
+// Якщо запит прийнято, після обробки треба викликати release.
+func admit(rw http.ResponseWriter, r *http.Request) (release func(), ok bool) {
+	mu.Lock()
+	l := limits
+	mu.Unlock()
+
+	if ok, wait := l.allow(r); !ok {
+		writeRetryAfter(rw, wait)
+		http.Error(rw, "Too many requests", http.StatusTooManyRequests)
+		return nil, false
+	}
+	if !l.acquire(r) {
+		writeRetryAfter(rw, l.cfg.queueTimeout())
+		http.Error(rw, "Load balancer is overloaded", http.StatusServiceUnavailable)
+		return nil, false
+	}
+	return l.release, true
+}
+
+// allow перевіряє обмеження частоти для клієнта й маршруту.
+// Якщо запит відхилено, повертає, через скільки варто повторити.
+func (l *limiter) allow(r *http.Request) (bool, time.Duration) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	t := now()
+
+	if l.cfg.PerIP != nil {
+		ip := clientIP(r)
+		b, ok := l.clients[ip]
+		if !ok {
+			if len(l.clients) >= maxTrackedClients {
+				l.pruneClients(t)
+			}
+			b = newTokenBucket(*l.cfg.PerIP, t)
+			l.clients[ip] = b
+		}
+		if ok, wait := b.take(t); !ok {
+			return false, wait
+		}
+	}
+
+	best := -1
+	for i, rl := range l.cfg.Routes {
+		if (best < 0 || len(rl.PathPrefix) > len(l.cfg.Routes[best].PathPrefix)) &&
+			matchesPathAndMethod(r, rl.PathPrefix, rl.Methods) {
+			best = i
+		}
+	}
+	if best >= 0 {
+		return l.routes[best].take(t)
+	}
+	return true, 0
+}
+
+// pruneClients прибирає повні відра. Якщо активних клієнтів однаково забагато,
+// забуває довільні з них, доки не лишиться clientsAfterEvict: пам'ять обмежено,
+// хоча забуті клієнти й отримують повне відро.
+func (l *limiter) pruneClients(t time.Time) {
+	for ip, b := range l.clients {
+		if b.refill(t); b.tokens >= b.burst {
+			delete(l.clients, ip)
+		}
+	}
+	for ip := range l.clients {
+		if len(l.clients) <= clientsAfterEvict {
+			break
+		}
+		delete(l.clients, ip)
+	}
+}
+
+// enqueue займає місце в черзі, якщо воно є.
+func (l *limiter) enqueue() bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.queued >= l.cfg.QueueSize {
+		return false
+	}
+	l.queued++
+	return true
+}
+
+func (l *limiter) dequeue() {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.queued--
+}
+
+// acquire займає місце серед MaxInFlight запитів, за потреби чекаючи в черзі.
+func (l *limiter) acquire(r *http.Request) bool {
+	if l.slots == nil {
+		return true
+	}
+	select {
+	case l.slots <- struct{}{}:
+		return true
+	default:
+	}
+	if !l.enqueue() {
+		return false
+	}
+	defer l.dequeue()
+	timer := time.NewTimer(l.cfg.queueTimeout())
+	defer timer.Stop()
+	select {
+	case l.slots <- struct{}{}:
+		return true
+	case <-timer.C:
+		return false
+	case <-r.Context().Done():
+		return false
+	}
+}
+
+func (l *limiter) release() {
+	if l.slots != nil {
+		<-l.slots
+	}
+}
+
+// backendFreed сигналізує про завершення запиту до бекенда тим, хто чекає
+// на вільне місце при MaxInFlightPerBackend. Використовує mu.
+var backendFreed = sync.NewCond(&mu)
+
+// backendWait — очікування на вільне місце в пулі, усі бекенди якого досягли MaxInFlightPerBackend.
+type backendWait struct {
+	queue    *limiter // обмеження, у черзі яких зайнято місце
+	deadline time.Time
+	timer    *time.Timer
+	stop     func() bool // скасовує пробудження при завершенні контексту запиту
+}
+
+// wakeWaiters прокидає всіх, хто чекає на backendFreed, щоб вони перевірили свій
+// дедлайн і контекст, навіть якщо місце не звільнилося.
+func wakeWaiters() {
+	mu.Lock()
+	defer mu.Unlock()
+	backendFreed.Broadcast()
+}
+
+// wait чекає на завершення будь-якого запиту до бекенда. Повертає false, якщо
+// черга заповнена, час очікування вичерпано або клієнт пішов. Викликається під mu.
+func (w *backendWait) wait(r *http.Request) bool {
+	if w.queue == nil {
+		if !limits.enqueue() {
+			return false
+		}
+		w.queue = limits
+		timeout := limits.cfg.queueTimeout()
+		w.deadline = now().Add(timeout)
+		w.timer = time.AfterFunc(timeout, wakeWaiters)
+		w.stop = context.AfterFunc(r.Context(), wakeWaiters)
+	}
+	if !now().Before(w.deadline) || r.Context().Err() != nil {
+		return false
+	}
+	backendFreed.Wait()
+	return true
+}
+
+func (w *backendWait) done() {
+	if w.queue != nil {
+		w.timer.Stop()
+		w.stop()
+		w.queue.dequeue()
+	}
+}
+
+// saturated повідомляє, що бекенд досяг MaxInFlightPerBackend. Викликається під mu.
+func saturated(server *BackendServer) bool {
+	max := limits.cfg.MaxInFlightPerBackend
+	return max > 0 && server.ActiveConns >= int64(max)
+}
+
+func writeRetryAfter(rw http.ResponseWriter, wait time.Duration) {
+	secs := max(int(math.Ceil(wait.Seconds())), 1)
+	rw.Header().Set("Retry-After", strconv.Itoa(secs))
+}
@@ -0,0 +1,266 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func useLimits(t *testing.T, cfg Limits) {
+	t.Helper()
+	mu.Lock()
+	applyLimits(&cfg)
+	mu.Unlock()
+	t.Cleanup(func() {
+		mu.Lock()
+		defer mu.Unlock()
+		applyLimits(nil)
+	})
+}
+
+// blockingBackend тримає кожен запит, доки тест не надішле значення в release.
+func blockingBackend(t *testing.T) (started <-chan struct{}, release chan<- struct{}) {
+	t.Helper()
+	s, r := make(chan struct{}, 10), make(chan struct{}, 10)
+	useProxyBackend(t, func(rw http.ResponseWriter, req *http.Request) {
+		s <- struct{}{}
+		<-r
+	})
+	t.Cleanup(func() { close(r) })
+	return s, r
+}
+
+func sendFrom(ip, target string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodGet, target, nil)
+	req.RemoteAddr = ip + ":1234"
+	rec := httptest.NewRecorder()
+	handleRequest(rec, req)
+	return rec
+}
+
+func TestTokenBucket(t *testing.T) {
+	advance := setNow(t)
+	b := newTokenBucket(Bucket{Rate: 2, Burst: 3}, now())
+
+	for i := 0; i < 3; i++ {
+		ok, _ := b.take(now())
+		require.True(t, ok)
+	}
+	ok, wait := b.take(now())
+	require.False(t, ok)
+	require.Equal(t, 500*time.Millisecond, wait)
+
+	advance(500 * time.Millisecond)
+	ok, _ = b.take(now())
+	require.True(t, ok)
+
+	// Відро не наповнюється понад Burst.
+	advance(time.Hour)
+	require.Equal(t, 3.0, func() float64 { b.refill(now()); return b.tokens }())
+	require.Equal(t, 1.0, newTokenBucket(Bucket{Rate: 0.5}, now()).burst)
+}
+
+func TestRateLimit_PerIP(t *testing.T) {
+	advance := setNow(t)
+	useBackends(t, newTestBackend(t))
+	useLimits(t, Limits{PerIP: &Bucket{Rate: 1, Burst: 2}})
+
+	require.Equal(t, http.StatusOK, sendFrom("192.0.2.1", "/").Code)
+	require.Equal(t, http.StatusOK, sendFrom("192.0.2.1", "/").Code)
+	rec := sendFrom("192.0.2.1", "/")
+	require.Equal(t, http.StatusTooManyRequests, rec.Code)
+	require.Equal(t, "1", rec.Header().Get("Retry-After"))
+
+	require.Equal(t, http.StatusOK, sendFrom("192.0.2.2", "/").Code, "other clients have their own bucket")
+
+	advance(time.Second)
+	require.Equal(t, http.StatusOK, sendFrom("192.0.2.1", "/").Code)
+}
+
+func TestRateLimit_PerRoute(t *testing.T) {
+	setNow(t)
+	useBackends(t, newTestBackend(t))
+	useLimits(t, Limits{Routes: []RouteRateLimit{
+		{PathPrefix: "/api", Bucket: Bucket{Rate: 100}},
+		{PathPrefix: "/api/report", Bucket: Bucket{Rate: 1}},
+	}})
+
+	// Відро маршруту спільне для всіх клієнтів.
+	require.Equal(t, http.StatusOK, sendFrom("192.0.2.1", "/api/report").Code)
+	require.Equal(t, http.StatusTooManyRequests, sendFrom("192.0.2.2", "/api/report").Code)
+	for i := 0; i < 10; i++ {
+		require.Equal(t, http.StatusOK, sendFrom("192.0.2.2", "/api/v1/some-data").Code)
+	}
+	require.Equal(t, http.StatusOK, sendFrom("192.0.2.2", "/other").Code)
+}
+
+func TestMaxInFlight(t *testing.T) {
+	started, release := blockingBackend(t)
+	useLimits(t, Limits{MaxInFlight: 1, QueueSize: 1, QueueTimeout: Duration(time.Minute)})
+
+	results := make(chan int, 2)
+	go func() { results <- sendFrom("192.0.2.1", "/").Code }()
+	<-started
+
+	// Другий запит стає в чергу, третьому місця в черзі вже немає.
+	go func() { results <- sendFrom("192.0.2.2", "/").Code }()
+	require.Eventually(t, func() bool {
+		limits.mu.Lock()
+		defer limits.mu.Unlock()
+		return limits.queued == 1
+	}, time.Second, time.Millisecond)
+	rec := sendFrom("192.0.2.3", "/")
+	require.Equal(t, http.StatusServiceUnavailable, rec.Code)
+	require.NotEmpty(t, rec.Header().Get("Retry-After"))
+
+	release <- struct{}{}
+	require.Equal(t, http.StatusOK, <-results)
+	<-started
+	release <- struct{}{}
+	require.Equal(t, http.StatusOK, <-results)
+}
+
+func TestMaxInFlight_QueueTimeout(t *testing.T) {
+	started, release := blockingBackend(t)
+	useLimits(t, Limits{MaxInFlight: 1, QueueSize: 1, QueueTimeout: Duration(10 * time.Millisecond)})
+
+	done := make(chan int)
+	go func() { done <- sendFrom("192.0.2.1", "/").Code }()
+	<-started
+	require.Equal(t, http.StatusServiceUnavailable, sendFrom("192.0.2.2", "/").Code)
+	release <- struct{}{}
+	require.Equal(t, http.StatusOK, <-done)
+}
+
+func TestMaxInFlightPerBackend(t *testing.T) {
+	started, release := blockingBackend(t)
+	useLimits(t, Limits{MaxInFlightPerBackend: 1, QueueSize: 1, QueueTimeout: Duration(time.Minute)})
+
+	results := make(chan int, 2)
+	go func() { results <- sendFrom("192.0.2.1", "/").Code }()
+	<-started
+	go func() { results <- sendFrom("192.0.2.2", "/").Code }()
+	require.Eventually(t, func() bool {
+		limits.mu.Lock()
+		defer limits.mu.Unlock()
+		return limits.queued == 1
+	}, time.Second, time.Millisecond)
+	rec := sendFrom("192.0.2.3", "/")
+	require.Equal(t, http.StatusServiceUnavailable, rec.Code, "queue is full")
+	require.Equal(t, "60", rec.Header().Get("Retry-After"))
+	require.Contains(t, rec.Body.String(), "overloaded")
+
+	select {
+	case <-started:
+		t.Fatal("the backend must not get a second concurrent request")
+	default:
+	}
+	release <- struct{}{}
+	require.Equal(t, http.StatusOK, <-results)
+	<-started
+	release <- struct{}{}
+	require.Equal(t, http.StatusOK, <-results)
+}
+
+func TestMaxInFlightPerBackend_QueueTimeout(t *testing.T) {
+	advance := setNow(t)
+	started, release := blockingBackend(t)
+	useLimits(t, Limits{MaxInFlightPerBackend: 1, QueueSize: 1, QueueTimeout: Duration(time.Minute)})
+
+	first := make(chan int, 1)
+	go func() { first <- sendFrom("192.0.2.1", "/").Code }()
+	<-started
+	second := make(chan *httptest.ResponseRecorder, 1)
+	go func() { second <- sendFrom("192.0.2.2", "/") }()
+	queued := func() bool {
+		limits.mu.Lock()
+		defer limits.mu.Unlock()
+		return limits.queued == 1
+	}
+	require.Eventually(t, queued, time.Second, time.Millisecond)
+
+	// Час очікування рахується за годинником балансувальника.
+	mu.Lock()
+	advance(time.Minute)
+	backendFreed.Broadcast()
+	mu.Unlock()
+	rec := <-second
+	require.Equal(t, http.StatusServiceUnavailable, rec.Code)
+	require.Equal(t, "60", rec.Header().Get("Retry-After"))
+	require.Zero(t, limits.queued)
+
+	release <- struct{}{}
+	require.Equal(t, http.StatusOK, <-first)
+}
+
+func TestMaxInFlightPerBackend_ClientGone(t *testing.T) {
+	started, release := blockingBackend(t)
+	useLimits(t, Limits{MaxInFlightPerBackend: 1, QueueSize: 1, QueueTimeout: Duration(time.Minute)})
+
+	first := make(chan int, 1)
+	go func() { first <- sendFrom("192.0.2.1", "/").Code }()
+	<-started
+
+	ctx, cancel := context.WithCancel(context.Background())
+	second := make(chan struct{})
+	go func() {
+		defer close(second)
+		handleRequest(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil).WithContext(ctx))
+	}()
+	require.Eventually(t, func() bool {
+		limits.mu.Lock()
+		defer limits.mu.Unlock()
+		return limits.queued == 1
+	}, time.Second, time.Millisecond)
+
+	// Клієнт, що пішов, звільняє чергу, не чекаючи QueueTimeout.
+	cancel()
+	select {
+	case <-second:
+	case <-time.After(time.Second):
+		t.Fatal("the cancelled request is still waiting")
+	}
+	require.Zero(t, limits.queued)
+
+	release <- struct{}{}
+	require.Equal(t, http.StatusOK, <-first)
+}
+
+func TestRateLimit_TrackedClientsAreCapped(t *testing.T) {
+	setNow(t)
+	l := newLimiter(Limits{PerIP: &Bucket{Rate: 1, Burst: 2}})
+	for i := 0; i < 2*maxTrackedClients; i++ {
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		req.RemoteAddr = fmt.Sprintf("10.%d.%d.%d:1234", i>>16&0xff, i>>8&0xff, i&0xff)
+		ok, _ := l.allow(req)
+		require.True(t, ok)
+	}
+	// Жодне відро не повне, тож обмежити пам'ять можна лише витісненням.
+	require.LessOrEqual(t, len(l.clients), maxTrackedClients)
+}
+
+func TestLimitsConfig(t *testing.T) {
+	require.NoError(t, Config{Limits: &Limits{PerIP: &Bucket{Rate: 5}, MaxInFlight: 100}}.validate())
+	for _, l := range []Limits{
+		{PerIP: &Bucket{}},
+		{Routes: []RouteRateLimit{{PathPrefix: "api", Bucket: Bucket{Rate: 1}}}},
+		{MaxInFlight: -1},
+	} {
+		require.Error(t, Config{Limits: &l}.validate(), "%+v", l)
+	}
+
+	// Незмінені обмеження зберігають стан відер при перезавантаженні.
+	useLimits(t, Limits{PerIP: &Bucket{Rate: 1}})
+	before := limits
+	mu.Lock()
+	applyLimits(&Limits{PerIP: &Bucket{Rate: 1}})
+	require.Same(t, before, limits)
+	applyLimits(&Limits{PerIP: &Bucket{Rate: 2}})
+	require.NotSame(t, before, limits)
+	mu.Unlock()
+}
@@ -0,0 +1,184 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+)
+
+const (
+	defaultConsecutiveErrors  = 5
+	defaultErrorRate          = 0.5
+	defaultErrorRateWindow    = 20
+	defaultBaseEjection       = 10 * time.Second
+	defaultMaxEjection        = 5 * time.Minute
+	defaultMaxEjectionPercent = 50
+	maxEjectionShift          = 10 // обмеження степеня, щоб 2^n не переповнився
+)
+
+// OutlierDetection — пасивна перевірка здоров'я за результатами живого трафіку.
+// Помилкою вважається збій з'єднання або відповідь 5xx.
+type OutlierDetection struct {
+	Disabled           bool     `json:"disabled,omitempty"`
+	ConsecutiveErrors  int      `json:"consecutiveErrors,omitempty"`  // помилок поспіль для вилучення
+	ErrorRate          float64  `json:"errorRate,omitempty"`          // частка помилок у вікні для вилучення
+	ErrorRateWindow    int      `json:"errorRateWindow,omitempty"`    // розмір вікна останніх запитів
+	BaseEjection       Duration `json:"baseEjection,omitempty"`       // тривалість першого вилучення
+	MaxEjection        Duration `json:"maxEjection,omitempty"`        // верхня межа тривалості вилучення
+	MaxEjectionPercent int      `json:"maxEjectionPercent,omitempty"` // частка бекендів, яку можна вилучити одночасно
+}
+
+var outlierDetection = OutlierDetection{}.withDefaults()
+
+func (od OutlierDetection) withDefaults() OutlierDetection {
+	if od.ConsecutiveErrors <= 0 {
+		od.ConsecutiveErrors = defaultConsecutiveErrors
+	}
+	if od.ErrorRate <= 0 {
+		od.ErrorRate = defaultErrorRate
+	}
+	if od.ErrorRateWindow <= 0 {
+		od.ErrorRateWindow = defaultErrorRateWindow
+	}
+	if od.BaseEjection <= 0 {
+		od.BaseEjection = Duration(defaultBaseEjection)
+	}
+	if od.MaxEjection <= 0 {
+		od.MaxEjection = Duration(defaultMaxEjection)
+	}
+	if od.MaxEjectionPercent <= 0 {
+		od.MaxEjectionPercent = defaultMaxEjectionPercent
+	}
+	return od
+}
+
+func (od *OutlierDetection) validate() error {
+	if od == nil {
+		return nil
+	}
+	if od.ErrorRate < 0 || od.ErrorRate > 1 {
+		return fmt.Errorf("outlier error rate must be between 0 and 1")
+	}
+	if od.MaxEjectionPercent > 100 {
+		return fmt.Errorf("max ejection percent must not exceed 100")
+	}
+	return nil
+}
+
+func resolveOutlierDetection(od *OutlierDetection) OutlierDetection {
+	if od == nil {
+		return OutlierDetection{}.withDefaults()
+	}
+	return od.withDefaults()
+}
+
+// outlierState — результати останніх запитів до бекенда.
+type outlierState struct {
+	Consecutive  int       `json:"consecutiveErrors"`
+	Ejections    int       `json:"ejections"`
+	EjectedUntil time.Time `json:"ejectedUntil,omitzero"`
+
+	window   []bool // кільцевий буфер: true — помилка
+	pos      int
+	filled   int
+	failures int
+}
+
+func (s *outlierState) ejected(t time.Time) bool {
+	return t.Before(s.EjectedUntil)
+}
+
+func (s *outlierState) push(failed bool, size int) {
+	if len(s.window) != size {
+		s.window = make([]bool, size)
+		s.pos, s.filled, s.failures = 0, 0, 0
+	}
+	if s.filled == size && s.window[s.pos] {
+		s.failures--
+	}
+	s.window[s.pos] = failed
+	if failed {
+		s.failures++
+	}
+	s.pos = (s.pos + 1) % size
+	s.filled = min(s.filled+1, size)
+}
+
+func (s *outlierState) reset() {
+	s.Consecutive = 0
+	s.window = nil
+	s.pos, s.filled, s.failures = 0, 0, 0
+}
+
+func isFailure(status int, err error) bool {
+	return err != nil || status >= 500
+}
+
+// clientCanceled повідомляє, що спробу перервав клієнт, який закрив з'єднання,
+// тож її результат нічого не каже про здоров'я бекенда.
+func clientCanceled(r *http.Request, err error) bool {
+	return errors.Is(err, context.Canceled) && r.Context().Err() != nil
+}
+
+// recordOutcome враховує результат запиту й за потреби вилучає бекенд
+// з ротації на 2^(n-1)*BaseEjection. Повертає true, якщо бекенд щойно вилучено.
+// Викликається під mu.
+func recordOutcome(server *BackendServer, failed bool, t time.Time) bool {
+	od := outlierDetection
+	s := &server.outlier
+	if od.Disabled || s.ejected(t) {
+		return false
+	}
+
+	if failed {
+		s.Consecutive++
+	} else {
+		s.Consecutive = 0
+	}
+	s.push(failed, od.ErrorRateWindow)
+
+	rateExceeded := s.filled == od.ErrorRateWindow &&
+		float64(s.failures) >= od.ErrorRate*float64(od.ErrorRateWindow)
+	if s.Consecutive < od.ConsecutiveErrors && !rateExceeded {
+		return false
+	}
+	if !canEject(server.Pool, t, od.MaxEjectionPercent) {
+		return false
+	}
+
+	// Якщо бекенд довго працював без вилучень, починаємо з базового періоду.
+	if !s.EjectedUntil.IsZero() && t.Sub(s.EjectedUntil) > time.Duration(od.MaxEjection) {
+		s.Ejections = 0
+	}
+	s.Ejections++
+	d := time.Duration(od.BaseEjection) << min(s.Ejections-1, maxEjectionShift)
+	s.EjectedUntil = t.Add(min(d, time.Duration(od.MaxEjection)))
+	s.reset()
+	return true
+}
+
+// canEject не дозволяє вилучити більше maxPercent відсотків бекендів пулу.
+func canEject(pool string, t time.Time, maxPercent int) bool {
+	total, ejected := 0, 0
+	for _, server := range backendStats {
+		if server.Pool != pool {
+			continue
+		}
+		total++
+		if server.outlier.ejected(t) {
+			ejected++
+		}
+	}
+	return (ejected+1)*100 <= maxPercent*total
+}
+
+// trackOutcome — recordOutcome з логуванням для обробника запитів. Викликається під mu.
+func trackOutcome(server *BackendServer, st forwardStats, err error) {
+	if recordOutcome(server, isFailure(st.StatusCode, err), now()) {
+		log.Printf("Backend %s ejected until %s after failed requests",
+			server.Address, server.outlier.EjectedUntil.Format(time.RFC3339))
+	}
+}
@@ -0,0 +1,194 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+// testBackend — httptest-бекенд, який можна перемкнути у режим помилок.
+type testBackend struct {
+	*httptest.Server
+	failing atomic.Bool
+	hits    atomic.Int32
+}
+
+func newTestBackend(t *testing.T) *testBackend {
+	t.Helper()
+	b := &testBackend{}
+	b.Server = httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		b.hits.Add(1)
+		if b.failing.Load() {
+			rw.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		_, _ = rw.Write([]byte("ok"))
+	}))
+	t.Cleanup(b.Close)
+	return b
+}
+
+func (b *testBackend) addr() string {
+	return strings.TrimPrefix(b.URL, "http://")
+}
+
+// useBackends робить тестові бекенди єдиними здоровими бекендами балансувальника.
+func useBackends(t *testing.T, backends ...*testBackend) {
+	t.Helper()
+	timeout = time.Second
+	backendStats = make(map[string]*BackendServer)
+	for _, b := range backends {
+		backendStats[b.addr()] = &BackendServer{Address: b.addr(), Healthy: true}
+	}
+	strategy = &roundRobin{}
+	t.Cleanup(func() { strategy = leastTraffic{} })
+}
+
+func sendRequests(n int) (statuses []int) {
+	for i := 0; i < n; i++ {
+		rec := httptest.NewRecorder()
+		handleRequest(rec, httptest.NewRequest(http.MethodGet, "/api/v1/some-data", nil))
+		statuses = append(statuses, rec.Code)
+	}
+	return statuses
+}
+
+func TestOutlier_ConsecutiveErrorsEject(t *testing.T) {
+	advance := setNow(t)
+	outlierDetection = OutlierDetection{ConsecutiveErrors: 3, BaseEjection: Duration(10 * time.Second)}.withDefaults()
+	t.Cleanup(func() { outlierDetection = OutlierDetection{}.withDefaults() })
+	useCircuitBreaker(t, CircuitBreaker{Disabled: true})
+	useSlowStart(t, 0)
+
+	good, bad := newTestBackend(t), newTestBackend(t)
+	useBackends(t, good, bad)
+	bad.failing.Store(true)
+
+	sendRequests(6) // по 3 запити кожному
+	require.EqualValues(t, 3, bad.hits.Load())
+	require.True(t, backendStats[bad.addr()].outlier.ejected(now()))
+
+	// Під час вилучення весь трафік іде на справний бекенд.
+	for _, status := range sendRequests(10) {
+		require.Equal(t, http.StatusOK, status)
+	}
+	require.EqualValues(t, 3, bad.hits.Load())
+
+	// Після завершення періоду бекенд повертається; повторне вилучення вдвічі довше.
+	advance(11 * time.Second)
+	sendRequests(6)
+	require.EqualValues(t, 6, bad.hits.Load())
+	state := backendStats[bad.addr()].outlier
+	require.Equal(t, 2, state.Ejections)
+	require.Equal(t, now().Add(20*time.Second), state.EjectedUntil)
+}
+
+func TestOutlier_ErrorRateEject(t *testing.T) {
+	setNow(t)
+	outlierDetection = OutlierDetection{ConsecutiveErrors: 100, ErrorRate: 0.5, ErrorRateWindow: 10}.withDefaults()
+	t.Cleanup(func() { outlierDetection = OutlierDetection{}.withDefaults() })
+
+	server := &BackendServer{Address: "srv"}
+	backendStats = map[string]*BackendServer{"srv": server, "other": {Address: "other"}}
+
+	// Помилки через одну: поспіль їх ніколи не більше однієї.
+	ejected := false
+	for i := 0; i < 10 && !ejected; i++ {
+		ejected = recordOutcome(server, i%2 == 0, now())
+	}
+	require.True(t, ejected)
+}
+
+func TestOutlier_ConnectionErrors(t *testing.T) {
+	setNow(t)
+	outlierDetection = OutlierDetection{ConsecutiveErrors: 2}.withDefaults()
+	t.Cleanup(func() { outlierDetection = OutlierDetection{}.withDefaults() })
+
+	good, dead := newTestBackend(t), newTestBackend(t)
+	useBackends(t, good, dead)
+	dead.Close()
+
+	sendRequests(4)
+	require.True(t, backendStats[dead.addr()].outlier.ejected(now()))
+	for _, status := range sendRequests(4) {
+		require.Equal(t, http.StatusOK, status)
+	}
+}
+
+func TestOutlier_MaxEjectionPercent(t *testing.T) {
+	setNow(t)
+	outlierDetection = OutlierDetection{ConsecutiveErrors: 1}.withDefaults()
+	t.Cleanup(func() { outlierDetection = OutlierDetection{}.withDefaults() })
+
+	a, b := &BackendServer{Address: "a"}, &BackendServer{Address: "b"}
+	backendStats = map[string]*BackendServer{"a": a, "b": b}
+
+	require.True(t, recordOutcome(a, true, now()))
+	// Вилучення другого з двох бекендів перевищило б 50%.
+	require.False(t, recordOutcome(b, true, now()))
+	require.False(t, b.outlier.ejected(now()))
+}
+
+func TestOutlier_MaxEjectionPercentPerPool(t *testing.T) {
+	setNow(t)
+	outlierDetection = OutlierDetection{ConsecutiveErrors: 1}.withDefaults()
+	t.Cleanup(func() { outlierDetection = OutlierDetection{}.withDefaults() })
+
+	backendStats = make(map[string]*BackendServer)
+	for _, addr := range []string{"a", "b", "c", "d"} {
+		backendStats[addr] = &BackendServer{Address: addr}
+	}
+	x, y := &BackendServer{Address: "x", Pool: "api"}, &BackendServer{Address: "y", Pool: "api"}
+	backendStats["x"], backendStats["y"] = x, y
+
+	// Межа рахується в межах пулу: великий сусідній пул не дозволяє вилучити весь "api".
+	require.True(t, recordOutcome(x, true, now()))
+	require.False(t, recordOutcome(y, true, now()))
+}
+
+func TestOutlier_Disabled(t *testing.T) {
+	setNow(t)
+	outlierDetection = OutlierDetection{Disabled: true, ConsecutiveErrors: 1}.withDefaults()
+	t.Cleanup(func() { outlierDetection = OutlierDetection{}.withDefaults() })
+
+	a := &BackendServer{Address: "a"}
+	backendStats = map[string]*BackendServer{"a": a, "b": {Address: "b"}}
+	require.False(t, recordOutcome(a, true, now()))
+}
+
+func TestOutlier_ClientCancelIsNotAFailure(t *testing.T) {
+	outlierDetection = OutlierDetection{ConsecutiveErrors: 1}.withDefaults()
+	t.Cleanup(func() { outlierDetection = OutlierDetection{}.withDefaults() })
+	useCircuitBreaker(t, CircuitBreaker{FailureThreshold: 1})
+
+	started := make(chan struct{})
+	useProxyBackend(t, func(rw http.ResponseWriter, r *http.Request) {
+		close(started)
+		<-r.Context().Done()
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		handleRequest(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil).WithContext(ctx))
+	}()
+	<-started
+	cancel()
+	<-done
+
+	mu.Lock()
+	defer mu.Unlock()
+	for _, server := range backendStats {
+		require.Zero(t, server.Errors)
+		require.Zero(t, server.ActiveConns)
+		require.False(t, server.outlier.ejected(now()))
+		require.Equal(t, circuitClosed, server.breaker.State)
+	}
+}
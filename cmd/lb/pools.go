@@ -0,0 +1,156 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"slices"
+	"strings"
+)
+
+var errPoolNotFound = errors.New("backend pool not found")
+
+// PoolConfig описує іменовану групу бекендів зі своєю стратегією та перевіркою здоров'я.
+type PoolConfig struct {
+	Name        string          `json:"name"`
+	Strategy    string          `json:"strategy,omitempty"` // за замовчуванням -strategy
+	HashOn      string          `json:"hashOn,omitempty"`   // за замовчуванням -hash-on
+	HealthCheck *HealthCheck    `json:"healthCheck,omitempty"`
+	Backends    []BackendConfig `json:"backends"`
+}
+
+// RouteConfig спрямовує запити, що відповідають усім заданим умовам, до пулу Pool.
+// Правила перевіряються по черзі; запити, які не підійшли жодному, обробляє
+// пул за замовчуванням з backends верхнього рівня.
+type RouteConfig struct {
+	Host       string            `json:"host,omitempty"` // точне ім'я або *.example.com
+	PathPrefix string            `json:"pathPrefix,omitempty"`
+	Methods    []string          `json:"methods,omitempty"`
+	Headers    map[string]string `json:"headers,omitempty"` // порожнє значення означає, що заголовок має бути присутнім
+	Pool       string            `json:"pool"`
+}
+
+// pool — стан іменованого пулу. Пул за замовчуванням має порожню назву
+// й використовує глобальну стратегію.
+type pool struct {
+	strategyName, hashOn string
+	strategy             Strategy
+	healthCheck          *HealthCheck
+}
+
+// pools і routes захищено mu.
+var (
+	pools  = make(map[string]*pool)
+	routes []RouteConfig
+)
+
+func (p PoolConfig) validate() error {
+	if p.Name == "" {
+		return fmt.Errorf("pool name must not be empty")
+	}
+	if p.Strategy != "" || p.HashOn != "" {
+		if _, err := newStrategy(p.strategyName(), p.hashOnAttribute()); err != nil {
+			return fmt.Errorf("pool %s: %w", p.Name, err)
+		}
+	}
+	if err := p.HealthCheck.validate(); err != nil {
+		return fmt.Errorf("pool %s: %w", p.Name, err)
+	}
+	return nil
+}
+
+func (p PoolConfig) strategyName() string {
+	if p.Strategy == "" {
+		return *strategyName
+	}
+	return p.Strategy
+}
+
+func (p PoolConfig) hashOnAttribute() string {
+	if p.HashOn == "" {
+		return *hashOn
+	}
+	return p.HashOn
+}
+
+func (rc RouteConfig) validate(known map[string]bool) error {
+	if !known[rc.Pool] {
+		return fmt.Errorf("route to unknown pool %q", rc.Pool)
+	}
+	if rc.PathPrefix != "" && !strings.HasPrefix(rc.PathPrefix, "/") {
+		return fmt.Errorf("route path prefix %q must start with /", rc.PathPrefix)
+	}
+	return nil
+}
+
+func (rc RouteConfig) matches(r *http.Request) bool {
+	if rc.Host != "" && !hostMatches(rc.Host, r.Host) {
+		return false
+	}
+	if !matchesPathAndMethod(r, rc.PathPrefix, rc.Methods) {
+		return false
+	}
+	for name, value := range rc.Headers {
+		got, ok := r.Header[http.CanonicalHeaderKey(name)]
+		if !ok || value != "" && !slices.Contains(got, value) {
+			return false
+		}
+	}
+	return true
+}
+
+func hostMatches(pattern, host string) bool {
+	if h, _, err := net.SplitHostPort(host); err == nil {
+		host = h
+	}
+	host = strings.ToLower(strings.TrimSuffix(host, "."))
+	pattern = strings.ToLower(pattern)
+	if suffix, ok := strings.CutPrefix(pattern, "*"); ok {
+		return strings.HasSuffix(host, suffix) && len(host) > len(suffix)
+	}
+	return host == pattern
+}
+
+// routeFor повертає назву пулу для запиту. Викликається під mu.
+func routeFor(r *http.Request) string {
+	for _, rc := range routes {
+		if rc.matches(r) {
+			return rc.Pool
+		}
+	}
+	return ""
+}
+
+// strategyFor повертає стратегію пулу. Викликається під mu.
+func strategyFor(name string) Strategy {
+	if p, ok := pools[name]; ok {
+		return p.strategy
+	}
+	return strategy
+}
+
+// poolHealthCheck повертає спільні налаштування перевірки пулу. Викликається під mu.
+func poolHealthCheck(name string) *HealthCheck {
+	if p, ok := pools[name]; ok {
+		return p.healthCheck
+	}
+	return nil
+}
+
+// applyPools замінює пули й маршрути. Стан стратегії пулу, налаштування
+// якої не змінилися, зберігається. Викликається під mu.
+func applyPools(cfgs []PoolConfig, rcs []RouteConfig) {
+	next := make(map[string]*pool, len(cfgs))
+	for _, pc := range cfgs {
+		p := &pool{strategyName: pc.strategyName(), hashOn: pc.hashOnAttribute(), healthCheck: pc.HealthCheck}
+		if old, ok := pools[pc.Name]; ok && old.strategyName == p.strategyName && old.hashOn == p.hashOn {
+			p.strategy = old.strategy
+		} else {
+			// Налаштування вже перевірено в Config.validate.
+			p.strategy, _ = newStrategy(p.strategyName, p.hashOn)
+		}
+		next[pc.Name] = p
+	}
+	pools, routes = next, rcs
+}
@@ -0,0 +1,149 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestRouteMatches(t *testing.T) {
+	req := func(method, target string, header ...string) *http.Request {
+		r := httptest.NewRequest(method, target, nil)
+		for i := 0; i < len(header); i += 2 {
+			r.Header.Set(header[i], header[i+1])
+		}
+		return r
+	}
+
+	for _, tc := range []struct {
+		route RouteConfig
+		req   *http.Request
+		want  bool
+	}{
+		{RouteConfig{PathPrefix: "/api/v1/"}, req("GET", "/api/v1/some-data"), true},
+		{RouteConfig{PathPrefix: "/api/v1/"}, req("GET", "/report"), false},
+		{RouteConfig{Host: "api.example.com"}, req("GET", "http://API.example.com:8090/"), true},
+		{RouteConfig{Host: "*.example.com"}, req("GET", "http://a.example.com/"), true},
+		{RouteConfig{Host: "*.example.com"}, req("GET", "http://example.com/"), false},
+		{RouteConfig{Methods: []string{"post"}}, req("POST", "/"), true},
+		{RouteConfig{Methods: []string{"POST"}}, req("GET", "/"), false},
+		{RouteConfig{Headers: map[string]string{"x-canary": ""}}, req("GET", "/", "X-Canary", "yes"), true},
+		{RouteConfig{Headers: map[string]string{"X-Canary": "1"}}, req("GET", "/", "X-Canary", "0"), false},
+		{RouteConfig{PathPrefix: "/api", Methods: []string{"GET"}}, req("POST", "/api"), false},
+	} {
+		require.Equal(t, tc.want, tc.route.matches(tc.req), "%+v %s %s", tc.route, tc.req.Method, tc.req.URL)
+	}
+}
+
+func TestPoolRouting(t *testing.T) {
+	api1, api2, report, fallback := newTestBackend(t), newTestBackend(t), newTestBackend(t), newTestBackend(t)
+	resetBackends(t)
+	t.Cleanup(func() { applyPools(nil, nil) })
+	*traceEnabled = true
+	t.Cleanup(func() { *traceEnabled = false })
+
+	applyConfig(Config{
+		Pools: []PoolConfig{
+			{Name: "api", Strategy: strategyRoundRobin, Backends: []BackendConfig{{Address: api1.addr()}, {Address: api2.addr()}}},
+			{Name: "report", Backends: []BackendConfig{{Address: report.addr()}}},
+		},
+		Routes: []RouteConfig{
+			{PathPrefix: "/api/v1/", Pool: "api"},
+			{PathPrefix: "/report", Pool: "report"},
+			{Host: "reports.example.com", Pool: "report"},
+		},
+		Backends: []BackendConfig{{Address: fallback.addr()}},
+	})
+	for _, s := range backendStats {
+		s.Healthy = true
+	}
+
+	send := func(target string) string {
+		rec := httptest.NewRecorder()
+		handleRequest(rec, httptest.NewRequest(http.MethodGet, target, nil))
+		require.Equal(t, http.StatusOK, rec.Code)
+		return rec.Header().Get("lb-from")
+	}
+
+	seen := map[string]bool{}
+	for i := 0; i < 4; i++ {
+		seen[send("/api/v1/some-data")] = true
+	}
+	require.Equal(t, map[string]bool{api1.addr(): true, api2.addr(): true}, seen, "api pool must use round-robin over its own backends")
+	require.Equal(t, report.addr(), send("/report"))
+	require.Equal(t, report.addr(), send("http://reports.example.com/anything"))
+	require.Equal(t, fallback.addr(), send("/other"))
+
+	// Нездоровий пул не віддає запити бекендам інших пулів.
+	backendStats[report.addr()].Healthy = false
+	rec := httptest.NewRecorder()
+	handleRequest(rec, httptest.NewRequest(http.MethodGet, "/report", nil))
+	require.Equal(t, http.StatusServiceUnavailable, rec.Code)
+}
+
+func TestPoolHealthCheckAndReload(t *testing.T) {
+	resetBackends(t)
+	t.Cleanup(func() { applyPools(nil, nil) })
+	cfg := Config{
+		HealthCheck: &HealthCheck{Rise: 5},
+		Pools: []PoolConfig{{
+			Name:        "report",
+			Strategy:    strategyRoundRobin,
+			HealthCheck: &HealthCheck{Path: "/ready"},
+			Backends: []BackendConfig{
+				{Address: "a:80"},
+				{Address: "b:80", HealthCheck: &HealthCheck{Path: "/live"}},
+			},
+		}},
+	}
+	applyConfig(cfg)
+	require.Equal(t, "report", backendStats["a:80"].Pool)
+	require.Equal(t, "/ready", backendStats["a:80"].HealthCheck.Path)
+	require.Equal(t, 5, backendStats["a:80"].HealthCheck.Rise)
+	require.Equal(t, "/live", backendStats["b:80"].HealthCheck.Path)
+
+	rr := pools["report"].strategy
+	applyConfig(cfg)
+	require.Same(t, rr, pools["report"].strategy, "unchanged pool must keep its strategy state")
+
+	// Бекенд переходить до пулу за замовчуванням, коли пул зникає з конфігурації.
+	applyConfig(Config{Backends: []BackendConfig{{Address: "a:80"}}})
+	require.Empty(t, backendStats["a:80"].Pool)
+	require.NotContains(t, backendStats, "b:80")
+	require.Empty(t, pools)
+}
+
+func TestPoolConfigValidation(t *testing.T) {
+	for name, cfg := range map[string]Config{
+		"route to unknown pool": {Routes: []RouteConfig{{PathPrefix: "/api", Pool: "api"}}},
+		"duplicate pool":        {Pools: []PoolConfig{{Name: "api"}, {Name: "api"}}},
+		"empty pool name":       {Pools: []PoolConfig{{}}},
+		"unknown strategy":      {Pools: []PoolConfig{{Name: "api", Strategy: "fastest"}}},
+		"backend in two pools": {
+			Pools:    []PoolConfig{{Name: "api", Backends: []BackendConfig{{Address: "a:80"}}}},
+			Backends: []BackendConfig{{Address: "a:80"}},
+		},
+		"backend with unknown pool": {Backends: []BackendConfig{{Address: "a:80", Pool: "api"}}},
+	} {
+		require.Error(t, cfg.validate(), name)
+	}
+}
+
+func TestAdminAddBackendToPool(t *testing.T) {
+	resetBackends(t)
+	t.Cleanup(func() { applyPools(nil, nil) })
+	applyConfig(Config{Pools: []PoolConfig{{Name: "api"}}})
+	h := adminHandler()
+
+	post := func(body string) int {
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, httptest.NewRequest("POST", "/backends", strings.NewReader(body)))
+		return rec.Code
+	}
+	require.Equal(t, http.StatusCreated, post(`{"address": "a:80", "pool": "api"}`))
+	require.Equal(t, "api", backendStats["a:80"].Pool)
+	require.Equal(t, http.StatusBadRequest, post(`{"address": "b:80", "pool": "missing"}`))
+}
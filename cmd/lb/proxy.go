@@ -0,0 +1,301 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"mime"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// hopHeaders стосуються одного з'єднання й не пересилаються далі (RFC 9110, розділ 7.6.1).
+var hopHeaders = []string{
+	"Connection",
+	"Proxy-Connection",
+	"Keep-Alive",
+	"Proxy-Authenticate",
+	"Proxy-Authorization",
+	"Te",
+	"Trailer",
+	"Transfer-Encoding",
+	"Upgrade",
+}
+
+// removeHopHeaders видаляє hop-by-hop заголовки, зокрема перелічені в Connection.
+func removeHopHeaders(h http.Header) {
+	for _, value := range h.Values("Connection") {
+		for _, name := range strings.Split(value, ",") {
+			if name = strings.TrimSpace(name); name != "" {
+				h.Del(name)
+			}
+		}
+	}
+	for _, name := range hopHeaders {
+		h.Del(name)
+	}
+}
+
+func hasToken(values []string, token string) bool {
+	for _, value := range values {
+		for _, v := range strings.Split(value, ",") {
+			if strings.EqualFold(strings.TrimSpace(v), token) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// upgradeType повертає протокол, на який клієнт просить перейти (наприклад, websocket).
+func upgradeType(h http.Header) string {
+	if !hasToken(h["Connection"], "Upgrade") {
+		return ""
+	}
+	return h.Get("Upgrade")
+}
+
+// setForwardedHeaders додає до запиту бекенду відомості про клієнта
+// у заголовках X-Forwarded-* і Forwarded (RFC 7239), зберігаючи записи попередніх проксі.
+func setForwardedHeaders(out, in *http.Request) {
+	ip := clientIP(in)
+	proto := "http"
+	if in.TLS != nil {
+		proto = "https"
+	}
+
+	if prior := in.Header.Values("X-Forwarded-For"); len(prior) > 0 {
+		out.Header.Set("X-Forwarded-For", strings.Join(prior, ", ")+", "+ip)
+	} else {
+		out.Header.Set("X-Forwarded-For", ip)
+	}
+	out.Header.Set("X-Forwarded-Proto", proto)
+	out.Header.Set("X-Forwarded-Host", in.Host)
+
+	node := ip
+	if strings.Contains(ip, ":") {
+		node = `"[` + ip + `]"`
+	}
+	elem := fmt.Sprintf("for=%s;host=%q;proto=%s", node, in.Host, proto)
+	if prior := in.Header.Values("Forwarded"); len(prior) > 0 {
+		elem = strings.Join(prior, ", ") + ", " + elem
+	}
+	out.Header.Set("Forwarded", elem)
+}
+
+// isStreaming визначає відповіді, які треба передавати клієнту без буферизації.
+func isStreaming(resp *http.Response) bool {
+	if resp.ContentLength == -1 {
+		return true
+	}
+	media, _, _ := mime.ParseMediaType(resp.Header.Get("Content-Type"))
+	return media == "text/event-stream"
+}
+
+// forward пересилає запит бекенду dst через client і передає його відповідь клієнту.
+// Помилка означає, що клієнту ще нічого не відправлено, тож запит можна повторити.
+func forward(client *http.Client, dst string, rw http.ResponseWriter, r *http.Request) (forwardStats, error) {
+	// Тайм-аут звичайних запитів задає handleRequest.
+	ctx, cancel := context.WithCancel(r.Context())
+	defer cancel()
+
+	// Для з'єднань, що перемикають протокол, тайм-аут стосується лише рукостискання.
+	var handshake *time.Timer
+	if upgradeType(r.Header) != "" {
+		handshake = time.AfterFunc(timeout, cancel)
+	}
+	resp, st, err := send(ctx, client, dst, r)
+	if handshake != nil {
+		handshake.Stop()
+	}
+	if err != nil {
+		return st, err
+	}
+	return respond(dst, rw, r, resp, st)
+}
+
+// send надсилає запит бекенду dst і повертає відповідь, тіло якої ще не прочитано.
+// Скасування ctx перериває запит разом із читанням тіла.
+func send(ctx context.Context, client *http.Client, dst string, r *http.Request) (*http.Response, forwardStats, error) {
+	var st forwardStats
+	upgrade := upgradeType(r.Header)
+
+	fwdRequest := r.Clone(ctx)
+	fwdRequest.RequestURI = ""
+	fwdRequest.URL.Host = dst
+	fwdRequest.URL.Scheme = scheme()
+	fwdRequest.Host = dst
+	fwdRequest.Close = false
+
+	removeHopHeaders(fwdRequest.Header)
+	if hasToken(r.Header["Te"], "trailers") {
+		fwdRequest.Header.Set("Te", "trailers")
+	}
+	if upgrade != "" {
+		fwdRequest.Header.Set("Connection", "Upgrade")
+		fwdRequest.Header.Set("Upgrade", upgrade)
+	}
+	if _, ok := fwdRequest.Header["User-Agent"]; !ok {
+		fwdRequest.Header.Set("User-Agent", "") // не підставляти User-Agent Go-клієнта
+	}
+	setForwardedHeaders(fwdRequest, r)
+	setDeadlineHeader(fwdRequest)
+
+	var body *countingReader
+	if r.Body != nil && r.Body != http.NoBody {
+		body = &countingReader{ReadCloser: r.Body}
+		fwdRequest.Body = body
+	}
+
+	start := now()
+	resp, err := client.Do(fwdRequest)
+	st.Latency = now().Sub(start)
+	if body != nil {
+		st.RequestBytes = body.n.Load()
+	}
+	if err != nil {
+		log.Printf("Failed to get response from %s: %s", dst, err)
+		return nil, st, err
+	}
+	return resp, st, nil
+}
+
+// respond передає клієнту відповідь бекенда dst і закриває її тіло.
+func respond(dst string, rw http.ResponseWriter, r *http.Request, resp *http.Response, st forwardStats) (forwardStats, error) {
+	defer resp.Body.Close()
+	if accessLog == nil {
+		log.Println("fwd", resp.StatusCode, resp.Request.URL)
+	}
+
+	if resp.StatusCode == http.StatusSwitchingProtocols {
+		return proxyUpgrade(dst, rw, r, resp, st)
+	}
+
+	removeHopHeaders(resp.Header)
+	copyHeader(rw.Header(), resp.Header)
+	if *traceEnabled {
+		rw.Header().Set("lb-from", dst)
+	}
+	announced := len(resp.Trailer)
+	if announced > 0 {
+		names := make([]string, 0, announced)
+		for name := range resp.Trailer {
+			names = append(names, name)
+		}
+		rw.Header().Set("Trailer", strings.Join(names, ", "))
+	}
+
+	st.StatusCode = resp.StatusCode
+	rw.WriteHeader(resp.StatusCode)
+	n, err := copyBody(rw, resp.Body, isStreaming(resp))
+	st.ResponseBytes = n
+	if err != nil {
+		log.Printf("Failed to write response: %s", err)
+		return st, nil
+	}
+
+	// Трейлери стають відомі лише після прочитання тіла.
+	for name, values := range resp.Trailer {
+		if len(resp.Trailer) != announced {
+			name = http.TrailerPrefix + name
+		}
+		for _, v := range values {
+			rw.Header().Add(name, v)
+		}
+	}
+	return st, nil
+}
+
+func copyHeader(dst, src http.Header) {
+	for k, values := range src {
+		for _, value := range values {
+			dst.Add(k, value)
+		}
+	}
+}
+
+// copyBody передає тіло відповіді клієнту; потокові відповіді скидаються після кожного фрагмента.
+func copyBody(rw http.ResponseWriter, body io.Reader, flush bool) (int64, error) {
+	rc := http.NewResponseController(rw)
+	buf := make([]byte, 32<<10)
+	var written int64
+	for {
+		n, rerr := body.Read(buf)
+		if n > 0 {
+			m, werr := rw.Write(buf[:n])
+			written += int64(m)
+			if werr != nil {
+				return written, werr
+			}
+			if flush {
+				if err := rc.Flush(); err != nil && !errors.Is(err, http.ErrNotSupported) {
+					return written, err
+				}
+			}
+		}
+		if rerr == io.EOF {
+			return written, nil
+		}
+		if rerr != nil {
+			return written, rerr
+		}
+	}
+}
+
+// proxyUpgrade завершує перемикання протоколу (наприклад, на WebSocket):
+// перехоплює з'єднання клієнта й передає байти в обидва боки, доки одна зі сторін не закриється.
+func proxyUpgrade(dst string, rw http.ResponseWriter, r *http.Request, resp *http.Response, st forwardStats) (forwardStats, error) {
+	backConn, ok := resp.Body.(io.ReadWriteCloser)
+	if !ok {
+		return st, fmt.Errorf("backend %s switched protocols without a writable body", dst)
+	}
+	conn, brw, err := http.NewResponseController(rw).Hijack()
+	if err != nil {
+		return st, fmt.Errorf("can't switch protocols: %w", err)
+	}
+	defer conn.Close()
+	_ = conn.SetDeadline(time.Time{}) // знімаємо таймаути HTTP-сервера
+
+	st.StatusCode = resp.StatusCode
+	if *traceEnabled {
+		resp.Header.Set("lb-from", dst)
+	}
+	res := &http.Response{
+		StatusCode: resp.StatusCode,
+		ProtoMajor: 1,
+		ProtoMinor: 1,
+		Header:     resp.Header,
+	}
+	if err := res.Write(brw); err != nil {
+		log.Printf("Failed to write upgrade response: %s", err)
+		return st, nil
+	}
+	if err := brw.Flush(); err != nil {
+		log.Printf("Failed to write upgrade response: %s", err)
+		return st, nil
+	}
+
+	// Коли одна зі сторін закриває з'єднання, закриваємо обидва.
+	done := make(chan struct{}, 2)
+	var sent, received int64
+	go func() {
+		// Клієнт міг надіслати дані одразу після запиту — вони вже в буфері brw.
+		sent, _ = io.Copy(backConn, brw)
+		done <- struct{}{}
+	}()
+	go func() {
+		received, _ = io.Copy(conn, backConn)
+		done <- struct{}{}
+	}()
+	<-done
+	_ = conn.Close()
+	_ = backConn.Close()
+	<-done
+
+	st.RequestBytes += sent
+	st.ResponseBytes = received
+	return st, nil
+}
@@ -0,0 +1,208 @@
+package main
+
+import (
+	"bufio"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+// useProxyBackend робить handler єдиним бекендом і повертає фронтенд балансувальника.
+func useProxyBackend(t *testing.T, handler http.HandlerFunc) *httptest.Server {
+	t.Helper()
+	backend := httptest.NewServer(handler)
+	t.Cleanup(backend.Close)
+	useBackends(t)
+	addr := backend.Listener.Addr().String()
+	backendStats[addr] = &BackendServer{Address: addr, Healthy: true}
+
+	frontend := httptest.NewServer(http.HandlerFunc(handleRequest))
+	t.Cleanup(frontend.Close)
+	return frontend
+}
+
+func TestProxy_HopByHopHeaders(t *testing.T) {
+	var got http.Header
+	frontend := useProxyBackend(t, func(rw http.ResponseWriter, r *http.Request) {
+		got = r.Header.Clone()
+		rw.Header().Set("Connection", "X-Backend-Hop")
+		rw.Header().Set("X-Backend-Hop", "secret")
+		rw.Header().Set("Proxy-Authenticate", "Basic")
+		rw.Header().Set("X-Kept", "yes")
+	})
+
+	req, err := http.NewRequest(http.MethodGet, frontend.URL, nil)
+	require.NoError(t, err)
+	req.Header.Set("Connection", "X-Client-Hop")
+	req.Header.Set("X-Client-Hop", "1")
+	req.Header.Set("Proxy-Authorization", "Basic Zm9vOmJhcg==")
+	req.Header.Set("Te", "trailers, deflate")
+	req.Header.Set("X-End-To-End", "1")
+	resp, err := http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	resp.Body.Close()
+
+	require.Empty(t, got.Get("X-Client-Hop"))
+	require.Empty(t, got.Get("Proxy-Authorization"))
+	require.Equal(t, "trailers", got.Get("Te"))
+	require.Equal(t, "1", got.Get("X-End-To-End"))
+
+	require.Empty(t, resp.Header.Get("X-Backend-Hop"))
+	require.Empty(t, resp.Header.Get("Proxy-Authenticate"))
+	require.Equal(t, "yes", resp.Header.Get("X-Kept"))
+}
+
+func TestProxy_ForwardedHeaders(t *testing.T) {
+	var got http.Header
+	backend := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		got = r.Header.Clone()
+	}))
+	t.Cleanup(backend.Close)
+	useBackends(t)
+	addr := backend.Listener.Addr().String()
+	backendStats[addr] = &BackendServer{Address: addr, Healthy: true}
+
+	for _, tc := range []struct {
+		remote, priorXFF, priorForwarded string
+		xff, forwarded                   string
+	}{
+		{
+			remote:    "192.0.2.1:1234",
+			xff:       "192.0.2.1",
+			forwarded: `for=192.0.2.1;host="example.com";proto=http`,
+		},
+		{
+			remote:         "[2001:db8::1]:1234",
+			priorXFF:       "203.0.113.5",
+			priorForwarded: "for=203.0.113.5",
+			xff:            "203.0.113.5, 2001:db8::1",
+			forwarded:      `for=203.0.113.5, for="[2001:db8::1]";host="example.com";proto=http`,
+		},
+	} {
+		req := httptest.NewRequest(http.MethodGet, "http://example.com/", nil)
+		req.RemoteAddr = tc.remote
+		if tc.priorXFF != "" {
+			req.Header.Set("X-Forwarded-For", tc.priorXFF)
+			req.Header.Set("Forwarded", tc.priorForwarded)
+		}
+		rec := httptest.NewRecorder()
+		handleRequest(rec, req)
+		require.Equal(t, http.StatusOK, rec.Code)
+
+		require.Equal(t, tc.xff, got.Get("X-Forwarded-For"))
+		require.Equal(t, "http", got.Get("X-Forwarded-Proto"))
+		require.Equal(t, "example.com", got.Get("X-Forwarded-Host"))
+		require.Equal(t, tc.forwarded, got.Get("Forwarded"))
+	}
+}
+
+func TestProxy_Trailers(t *testing.T) {
+	frontend := useProxyBackend(t, func(rw http.ResponseWriter, r *http.Request) {
+		rw.Header().Set("Trailer", "X-Checksum")
+		_, _ = rw.Write([]byte("payload"))
+		rw.Header().Set("X-Checksum", "abc")
+		rw.Header().Set(http.TrailerPrefix+"X-Late", "undeclared")
+	})
+
+	resp, err := http.Get(frontend.URL)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	require.NoError(t, err)
+	require.Equal(t, "payload", string(body))
+	require.Equal(t, "abc", resp.Trailer.Get("X-Checksum"))
+	require.Equal(t, "undeclared", resp.Trailer.Get("X-Late"))
+}
+
+func TestProxy_FlushesEventStream(t *testing.T) {
+	release := make(chan struct{})
+	frontend := useProxyBackend(t, func(rw http.ResponseWriter, r *http.Request) {
+		rw.Header().Set("Content-Type", "text/event-stream")
+		_, _ = rw.Write([]byte("data: first\n\n"))
+		rw.(http.Flusher).Flush()
+		<-release
+		_, _ = rw.Write([]byte("data: second\n\n"))
+	})
+	defer close(release)
+
+	resp, err := http.Get(frontend.URL)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+
+	line := make(chan string, 1)
+	go func() {
+		s, _ := bufio.NewReader(resp.Body).ReadString('\n')
+		line <- s
+	}()
+	select {
+	case s := <-line:
+		require.Equal(t, "data: first\n", s)
+	case <-time.After(2 * time.Second):
+		t.Fatal("the first event must reach the client before the stream ends")
+	}
+}
+
+func TestProxy_WebSocketUpgrade(t *testing.T) {
+	frontend := useProxyBackend(t, func(rw http.ResponseWriter, r *http.Request) {
+		if upgradeType(r.Header) != "websocket" {
+			http.Error(rw, "upgrade required", http.StatusUpgradeRequired)
+			return
+		}
+		conn, brw, err := http.NewResponseController(rw).Hijack()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		_, _ = brw.WriteString("HTTP/1.1 101 Switching Protocols\r\nUpgrade: websocket\r\nConnection: Upgrade\r\n\r\n")
+		_ = brw.Flush()
+		// Ехо-сервер замість справжнього протоколу WebSocket.
+		_, _ = io.Copy(conn, brw)
+	})
+
+	conn, err := net.Dial("tcp", frontend.Listener.Addr().String())
+	require.NoError(t, err)
+	defer conn.Close()
+	require.NoError(t, conn.SetDeadline(time.Now().Add(5*time.Second)))
+
+	_, err = io.WriteString(conn, "GET /ws HTTP/1.1\r\nHost: example.com\r\nConnection: Upgrade\r\nUpgrade: websocket\r\n\r\n")
+	require.NoError(t, err)
+	r := bufio.NewReader(conn)
+	resp, err := http.ReadResponse(r, nil)
+	require.NoError(t, err)
+	require.Equal(t, http.StatusSwitchingProtocols, resp.StatusCode)
+	require.Equal(t, "websocket", resp.Header.Get("Upgrade"))
+
+	for _, msg := range []string{"hello\n", "world\n"} {
+		_, err = io.WriteString(conn, msg)
+		require.NoError(t, err)
+		echo, err := r.ReadString('\n')
+		require.NoError(t, err)
+		require.Equal(t, msg, echo)
+	}
+
+	// Перехоплені з'єднання не закриваються разом із тестовим сервером, тож
+	// чекаємо, поки балансувальник завершить обробку, щоб не зачепити наступні тести.
+	require.NoError(t, conn.Close())
+	require.Eventually(t, func() bool { return inFlight() == 0 }, time.Second, time.Millisecond)
+}
+
+func TestRemoveHopHeaders(t *testing.T) {
+	h := http.Header{
+		"Connection":    {"close, X-A", "X-B"},
+		"X-A":           {"1"},
+		"X-B":           {"2"},
+		"Keep-Alive":    {"timeout=5"},
+		"Upgrade":       {"websocket"},
+		"X-End-To-End":  {"3"},
+		"Authorization": {"Bearer t"},
+	}
+	removeHopHeaders(h)
+	require.Equal(t, http.Header{"X-End-To-End": {"3"}, "Authorization": {"Bearer t"}}, h)
+	require.Equal(t, "websocket", upgradeType(http.Header{"Connection": {"keep-alive, Upgrade"}, "Upgrade": {"websocket"}}))
+	require.Empty(t, upgradeType(http.Header{"Upgrade": {"websocket"}}))
+}
@@ -0,0 +1,295 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net"
+	"os"
+	"slices"
+)
+
+var (
+	errBackendExists   = errors.New("backend already exists")
+	errBackendNotFound = errors.New("backend not found")
+	errInvalidBackend  = errors.New("invalid backend")
+)
+
+// BackendConfig описує один бекенд у файлі конфігурації.
+type BackendConfig struct {
+	Address     string       `json:"address"`
+	Pool        string       `json:"pool,omitempty"` // для бекендів, заданих поза pools
+	Weight      int          `json:"weight,omitempty"`
+	HealthCheck *HealthCheck `json:"healthCheck,omitempty"`
+	Transport   *Transport   `json:"transport,omitempty"`
+}
+
+// Config — вміст файлу, заданого прапорцем -config.
+type Config struct {
+	HealthCheck      *HealthCheck      `json:"healthCheck,omitempty"` // спільні налаштування перевірок
+	Transport        *Transport        `json:"transport,omitempty"`   // спільні налаштування пулів з'єднань
+	OutlierDetection *OutlierDetection `json:"outlierDetection,omitempty"`
+	CircuitBreaker   *CircuitBreaker   `json:"circuitBreaker,omitempty"`
+	Timeouts         []RouteTimeout    `json:"timeouts,omitempty"`
+	Limits           *Limits           `json:"limits,omitempty"`
+	Pools            []PoolConfig      `json:"pools,omitempty"`
+	Routes           []RouteConfig     `json:"routes,omitempty"`
+	Backends         []BackendConfig   `json:"backends"` // пул за замовчуванням
+}
+
+// allBackends повертає бекенди всіх пулів із заповненим полем Pool.
+func (c Config) allBackends() []BackendConfig {
+	res := slices.Clone(c.Backends)
+	for _, p := range c.Pools {
+		for _, b := range p.Backends {
+			b.Pool = p.Name
+			res = append(res, b)
+		}
+	}
+	return res
+}
+
+func loadConfig(path string) (Config, error) {
+	var cfg Config
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return cfg, err
+	}
+	if err := json.Unmarshal(data, &cfg); err != nil {
+		return cfg, fmt.Errorf("parse %s: %w", path, err)
+	}
+	return cfg, cfg.validate()
+}
+
+func (c Config) validate() error {
+	if err := c.HealthCheck.validate(); err != nil {
+		return err
+	}
+	if err := c.Transport.validate(); err != nil {
+		return err
+	}
+	if err := c.OutlierDetection.validate(); err != nil {
+		return err
+	}
+	if err := c.CircuitBreaker.validate(); err != nil {
+		return err
+	}
+	if err := c.Limits.validate(); err != nil {
+		return err
+	}
+	for _, rt := range c.Timeouts {
+		if err := rt.validate(); err != nil {
+			return err
+		}
+	}
+	known := map[string]bool{"": true}
+	for _, p := range c.Pools {
+		if err := p.validate(); err != nil {
+			return err
+		}
+		if known[p.Name] {
+			return fmt.Errorf("duplicate pool %s", p.Name)
+		}
+		known[p.Name] = true
+	}
+	for _, rc := range c.Routes {
+		if err := rc.validate(known); err != nil {
+			return err
+		}
+	}
+	seen := make(map[string]bool)
+	for _, b := range c.allBackends() {
+		if !known[b.Pool] {
+			return fmt.Errorf("backend %s: %w: %s", b.Address, errPoolNotFound, b.Pool)
+		}
+		if err := b.validate(); err != nil {
+			return err
+		}
+		if seen[b.Address] {
+			return fmt.Errorf("duplicate backend %s", b.Address)
+		}
+		seen[b.Address] = true
+	}
+	return nil
+}
+
+// validate перевіряє один бекенд; пул перевіряє той, хто знає набір пулів.
+func (b BackendConfig) validate() error {
+	if b.Address == "" {
+		return fmt.Errorf("backend address must not be empty")
+	}
+	if host, port, err := net.SplitHostPort(b.Address); err != nil || host == "" || port == "" {
+		return fmt.Errorf("backend address %q must be host:port", b.Address)
+	}
+	if b.Weight < 0 {
+		return fmt.Errorf("backend %s: weight must not be negative", b.Address)
+	}
+	if err := b.HealthCheck.validate(); err != nil {
+		return fmt.Errorf("backend %s: %w", b.Address, err)
+	}
+	if err := b.Transport.validate(); err != nil {
+		return fmt.Errorf("backend %s: %w", b.Address, err)
+	}
+	return nil
+}
+
+// defaultConfig відповідає фіксованому serversPool, коли -config не задано.
+func defaultConfig() Config {
+	var cfg Config
+	for _, addr := range serversPool {
+		cfg.Backends = append(cfg.Backends, BackendConfig{Address: addr})
+	}
+	return cfg
+}
+
+// addBackend реєструє бекенд і запускає для нього перевірку здоров'я.
+func addBackend(cfg BackendConfig) error {
+	if err := cfg.validate(); err != nil {
+		return fmt.Errorf("%w: %w", errInvalidBackend, err)
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	if _, ok := backendStats[cfg.Address]; ok {
+		return errBackendExists
+	}
+	if _, ok := pools[cfg.Pool]; cfg.Pool != "" && !ok {
+		return errPoolNotFound
+	}
+	server := &BackendServer{
+		Address:     cfg.Address,
+		Pool:        cfg.Pool,
+		Weight:      cfg.Weight,
+		HealthCheck: resolveHealthCheck(poolHealthCheck(cfg.Pool), cfg.HealthCheck),
+		stop:        make(chan struct{}),
+	}
+	setTransport(server, resolveTransport(cfg.Transport))
+	backendStats[cfg.Address] = server
+	go healthLoop(server)
+	log.Printf("Backend %s added", cfg.Address)
+	return nil
+}
+
+// removeBackend прибирає бекенд і зупиняє його перевірку здоров'я.
+// Запити, що вже обробляються, завершуються звичайним чином.
+func removeBackend(addr string) error {
+	mu.Lock()
+	defer mu.Unlock()
+	server, ok := backendStats[addr]
+	if !ok {
+		return errBackendNotFound
+	}
+	delete(backendStats, addr)
+	if server.stop != nil {
+		close(server.stop)
+	}
+	if server.client != nil {
+		server.client.CloseIdleConnections()
+		server.probeClient.CloseIdleConnections()
+	}
+	log.Printf("Backend %s removed, %d in-flight requests will complete", addr, server.ActiveConns)
+	return nil
+}
+
+// drainBackend припиняє надсилати бекенду нові запити, не видаляючи його.
+func drainBackend(addr string) error {
+	mu.Lock()
+	defer mu.Unlock()
+	server, ok := backendStats[addr]
+	if !ok {
+		return errBackendNotFound
+	}
+	server.Draining = true
+	log.Printf("Backend %s is draining", addr)
+	return nil
+}
+
+const (
+	overrideUp   = "up"   // бекенд отримує запити незалежно від активних перевірок
+	overrideDown = "down" // бекенд не отримує запитів незалежно від активних перевірок
+)
+
+// setOverride вручну позначає бекенд робочим чи непрацездатним; порожній
+// override повертає рішення активним перевіркам. Позначка "up" також скасовує drain.
+func setOverride(addr, override string) error {
+	mu.Lock()
+	defer mu.Unlock()
+	server, ok := backendStats[addr]
+	if !ok {
+		return errBackendNotFound
+	}
+	wasInRotation := inRotation(server)
+	server.Override = override
+	if override == overrideUp {
+		server.Draining = false
+	}
+	if !wasInRotation && inRotation(server) {
+		warmUp(server, now())
+	}
+	if override == "" {
+		log.Printf("Backend %s is managed by health checks", addr)
+	} else {
+		log.Printf("Backend %s is manually marked %s", addr, override)
+	}
+	return nil
+}
+
+// applyConfig застосовує загальні налаштування, пули й маршрути конфігурації
+// й приводить до неї набір бекендів.
+func applyConfig(cfg Config) {
+	mu.Lock()
+	defaultHealthCheck = HealthCheck{}.merge(cfg.HealthCheck)
+	defaultTransport = Transport{}.merge(cfg.Transport)
+	outlierDetection = resolveOutlierDetection(cfg.OutlierDetection)
+	circuitBreaker = resolveCircuitBreaker(cfg.CircuitBreaker)
+	routeTimeouts = cfg.Timeouts
+	applyLimits(cfg.Limits)
+	applyPools(cfg.Pools, cfg.Routes)
+	mu.Unlock()
+
+	applyBackends(cfg.allBackends())
+}
+
+// applyBackends приводить backendStats до списку backends: додає нові бекенди,
+// видаляє відсутні й оновлює пули, ваги, перевірки здоров'я та пули з'єднань наявних.
+// Статистика наявних бекендів зберігається, інші налаштування не змінюються.
+func applyBackends(backends []BackendConfig) {
+	wanted := make(map[string]BackendConfig, len(backends))
+	for _, b := range backends {
+		wanted[b.Address] = b
+	}
+
+	mu.Lock()
+	var stale []string
+	for addr, server := range backendStats {
+		if b, ok := wanted[addr]; ok {
+			server.Pool = b.Pool
+			server.Weight = b.Weight
+			server.HealthCheck = resolveHealthCheck(poolHealthCheck(b.Pool), b.HealthCheck)
+			setTransport(server, resolveTransport(b.Transport))
+			delete(wanted, addr)
+		} else {
+			stale = append(stale, addr)
+		}
+	}
+	mu.Unlock()
+
+	for _, addr := range stale {
+		_ = removeBackend(addr)
+	}
+	for _, b := range backends {
+		if _, ok := wanted[b.Address]; ok {
+			_ = addBackend(b)
+		}
+	}
+}
+
+func reloadConfig(path string) {
+	cfg, err := loadConfig(path)
+	if err != nil {
+		log.Printf("Config reload failed, keeping current backends: %s", err)
+		return
+	}
+	applyConfig(cfg)
+	log.Printf("Config reloaded from %s: %d backends in %d pools", path, len(cfg.allBackends()), len(cfg.Pools)+1)
+}
@@ -0,0 +1,188 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// resetBackends очищає глобальний реєстр і зупиняє перевірки здоров'я після тесту.
+func resetBackends(t *testing.T) {
+	t.Helper()
+	backendStats = make(map[string]*BackendServer)
+	t.Cleanup(func() {
+		for addr := range backendStats {
+			_ = removeBackend(addr)
+		}
+	})
+}
+
+func isStopped(server *BackendServer) bool {
+	select {
+	case <-server.stop:
+		return true
+	default:
+		return false
+	}
+}
+
+func TestApplyConfig(t *testing.T) {
+	resetBackends(t)
+
+	applyConfig(Config{Backends: []BackendConfig{
+		{Address: "a:80"}, {Address: "b:80", Weight: 2},
+	}})
+	require.Len(t, backendStats, 2)
+	a, b := backendStats["a:80"], backendStats["b:80"]
+	a.Traffic = 42
+
+	applyConfig(Config{Backends: []BackendConfig{
+		{Address: "a:80", Weight: 3}, {Address: "c:80"},
+	}})
+	require.Len(t, backendStats, 2)
+	require.Same(t, a, backendStats["a:80"], "existing backend must keep its stats")
+	require.EqualValues(t, 42, a.Traffic)
+	require.Equal(t, 3, a.Weight)
+	require.True(t, isStopped(b), "health check of a removed backend must stop")
+	require.False(t, isStopped(a))
+	require.Contains(t, backendStats, "c:80")
+}
+
+func TestLoadConfig(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "lb.json")
+
+	require.NoError(t, os.WriteFile(path, []byte(`{"backends": [{"address": "a:80", "weight": 2}]}`), 0o600))
+	cfg, err := loadConfig(path)
+	require.NoError(t, err)
+	require.Equal(t, []BackendConfig{{Address: "a:80", Weight: 2}}, cfg.Backends)
+
+	require.NoError(t, os.WriteFile(path, []byte(`{"backends": [{"address": "a:80"}, {"address": "a:80"}]}`), 0o600))
+	_, err = loadConfig(path)
+	require.Error(t, err)
+
+	require.NoError(t, os.WriteFile(path, []byte(`{`), 0o600))
+	_, err = loadConfig(path)
+	require.Error(t, err)
+}
+
+func TestReloadConfig_KeepsBackendsOnError(t *testing.T) {
+	resetBackends(t)
+	applyConfig(Config{Backends: []BackendConfig{{Address: "a:80"}}})
+
+	reloadConfig(filepath.Join(t.TempDir(), "missing.json"))
+	require.Contains(t, backendStats, "a:80")
+}
+
+func TestDrainedBackendGetsNoRequests(t *testing.T) {
+	resetBackends(t)
+	applyConfig(Config{Backends: []BackendConfig{{Address: "a:80"}, {Address: "b:80"}}})
+	for _, s := range backendStats {
+		s.Healthy = true
+	}
+
+	require.NoError(t, drainBackend("a:80"))
+	for i := 0; i < 5; i++ {
+		require.Equal(t, "b:80", getLeastTrafficServer().Address)
+	}
+	require.ErrorIs(t, drainBackend("missing:80"), errBackendNotFound)
+}
+
+func TestAdminAPI(t *testing.T) {
+	resetBackends(t)
+	h := adminHandler()
+
+	do := func(method, path, body string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, httptest.NewRequest(method, path, strings.NewReader(body)))
+		return rec
+	}
+
+	require.Equal(t, http.StatusCreated, do("POST", "/backends", `{"address": "a:80", "weight": 2}`).Code)
+	require.Equal(t, http.StatusConflict, do("POST", "/backends", `{"address": "a:80"}`).Code)
+	require.Equal(t, http.StatusBadRequest, do("POST", "/backends", `{}`).Code)
+	for _, body := range []string{
+		`{"address": "no-port"}`,
+		`{"address": "b:80", "weight": -1}`,
+		`{"address": "b:80", "healthCheck": {"path": "health"}}`,
+		`{"address": "b:80", "transport": {"maxConns": -1}}`,
+	} {
+		require.Equal(t, http.StatusBadRequest, do("POST", "/backends", body).Code, body)
+	}
+
+	require.Equal(t, http.StatusNoContent, do("POST", "/backends/a:80/drain", "").Code)
+
+	rec := do("GET", "/backends", "")
+	require.Equal(t, http.StatusOK, rec.Code)
+	var list []backendView
+	require.NoError(t, json.NewDecoder(rec.Body).Decode(&list))
+	require.Len(t, list, 1)
+	require.Equal(t, "a:80", list[0].Address)
+	require.Equal(t, 2, list[0].Weight)
+	require.True(t, list[0].Draining)
+
+	server := backendStats["a:80"]
+	require.Equal(t, http.StatusNoContent, do("DELETE", "/backends/a:80", "").Code)
+	require.True(t, isStopped(server))
+	require.Empty(t, backendStats)
+	require.Equal(t, http.StatusNotFound, do("DELETE", "/backends/a:80", "").Code)
+}
+
+func TestAdminStatusAndOverride(t *testing.T) {
+	good, bad := newTestBackend(t), newTestBackend(t)
+	useBackends(t, good, bad)
+	bad.failing.Store(true)
+	outlierDetection = OutlierDetection{Disabled: true}
+	t.Cleanup(func() { outlierDetection = OutlierDetection{}.withDefaults() })
+	useCircuitBreaker(t, CircuitBreaker{Disabled: true})
+	useSlowStart(t, 0)
+	h := adminHandler()
+
+	get := func(addr string) backendView {
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, httptest.NewRequest("GET", "/backends/"+addr, nil))
+		require.Equal(t, http.StatusOK, rec.Code)
+		var view backendView
+		require.NoError(t, json.NewDecoder(rec.Body).Decode(&view))
+		return view
+	}
+	post := func(path string) int {
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, httptest.NewRequest("POST", path, nil))
+		return rec.Code
+	}
+
+	sendRequests(4)
+	view := get(bad.addr())
+	require.EqualValues(t, 2, view.Errors)
+	require.NotNil(t, view.Latency)
+	require.Zero(t, get(good.addr()).Errors)
+
+	// Ручне вимкнення прибирає бекенд з ротації, хоча перевірки вважають його здоровим.
+	require.Equal(t, http.StatusNoContent, post("/backends/"+bad.addr()+"/down"))
+	require.Equal(t, overrideDown, get(bad.addr()).Override)
+	sendRequests(4)
+	require.EqualValues(t, 2, bad.hits.Load())
+
+	// Ручне ввімкнення повертає в ротацію навіть нездоровий бекенд, що ще й дренується.
+	backendStats[bad.addr()].Healthy = false
+	backendStats[bad.addr()].Draining = true
+	require.Equal(t, http.StatusNoContent, post("/backends/"+bad.addr()+"/up"))
+	sendRequests(4)
+	require.EqualValues(t, 4, bad.hits.Load())
+
+	require.Equal(t, http.StatusNoContent, post("/backends/"+bad.addr()+"/auto"))
+	sendRequests(4)
+	require.EqualValues(t, 4, bad.hits.Load(), "health checks decide again")
+
+	require.Equal(t, http.StatusNotFound, post("/backends/missing:80/down"))
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest("GET", "/backends/missing:80", nil))
+	require.Equal(t, http.StatusNotFound, rec.Code)
+}
@@ -0,0 +1,105 @@
+package main
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+)
+
+const minRetryTokens = 10
+
+// retryBudget обмежує частку повторів: кожен запит додає ratio токенів,
+// кожен повтор забирає один. Так збій бекенда не подвоює навантаження на решту.
+type retryBudget struct {
+	mu     sync.Mutex
+	ratio  float64
+	tokens float64
+	max    float64
+}
+
+func newRetryBudget(ratio float64) *retryBudget {
+	return &retryBudget{ratio: ratio, tokens: minRetryTokens, max: minRetryTokens + 100*ratio}
+}
+
+func (b *retryBudget) deposit() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.tokens = min(b.max, b.tokens+b.ratio)
+}
+
+func (b *retryBudget) withdraw() bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.tokens < 1 {
+		return false
+	}
+	b.tokens--
+	return true
+}
+
+var retries = newRetryBudget(0.2)
+
+// parseMethods розбирає список HTTP-методів, розділених комами.
+func parseMethods(list string) map[string]bool {
+	res := make(map[string]bool)
+	for _, m := range strings.Split(list, ",") {
+		if m = strings.ToUpper(strings.TrimSpace(m)); m != "" {
+			res[m] = true
+		}
+	}
+	return res
+}
+
+var retryMethods = parseMethods("GET,HEAD")
+
+// bufferBody зчитує тіло запиту, якщо воно не більше limit, щоб його можна
+// було надіслати повторно. Для більших тіл повертає false і відновлює r.Body.
+func bufferBody(r *http.Request, limit int64) ([]byte, bool) {
+	if r.Body == nil || r.Body == http.NoBody {
+		return nil, true
+	}
+	buf, err := io.ReadAll(io.LimitReader(r.Body, limit+1))
+	if err != nil || int64(len(buf)) > limit {
+		r.Body = struct {
+			io.Reader
+			io.Closer
+		}{io.MultiReader(bytes.NewReader(buf), r.Body), r.Body}
+		return nil, false
+	}
+	_ = r.Body.Close()
+	r.Body = io.NopCloser(bytes.NewReader(buf))
+	return buf, true
+}
+
+// canRetry визначає, чи можна повторити запит на іншому бекенді, і готує тіло для повтору.
+func canRetry(r *http.Request) (body []byte, ok bool) {
+	if *maxRetries <= 0 || !retryMethods[r.Method] {
+		return nil, false
+	}
+	return bufferBody(r, *retryBodyLimit)
+}
+
+// readRetryBody — canRetry, що читає тіло не довше за дедлайн контексту запиту.
+// Інакше клієнт, який надсилає тіло по байту, безстроково тримав би місце
+// серед MaxInFlight: тайм-аут маршруту діє лише на спроби, а не на читання.
+func readRetryBody(rw http.ResponseWriter, r *http.Request) ([]byte, bool) {
+	deadline, ok := r.Context().Deadline()
+	if !ok {
+		return canRetry(r)
+	}
+	// Дедлайн лишається й після буферизації: тіло, яке не вмістилося в буфер,
+	// дочитує forward, а після дедлайну воно вже нікому не потрібне. Для
+	// наступного запиту з'єднання сервер встановлює дедлайни заново.
+	_ = http.NewResponseController(rw).SetReadDeadline(deadline)
+	return canRetry(r)
+}
+
+func rewindBody(r *http.Request, body []byte) {
+	if body == nil {
+		r.Body = http.NoBody
+		return
+	}
+	r.Body = io.NopCloser(bytes.NewReader(body))
+}
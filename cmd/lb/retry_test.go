@@ -0,0 +1,90 @@
+package main
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestRetry_ConnectionErrorUsesAnotherBackend(t *testing.T) {
+	dead, good := newTestBackend(t), newTestBackend(t)
+	useBackends(t, dead, good)
+	dead.Close()
+	retries = newRetryBudget(0.2)
+	*traceEnabled = true
+	t.Cleanup(func() { *traceEnabled = false })
+
+	// Round-robin за адресою; перший запит може піти на будь-який бекенд, тож шлемо два.
+	for i := 0; i < 2; i++ {
+		rec := httptest.NewRecorder()
+		handleRequest(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+		require.Equal(t, http.StatusOK, rec.Code)
+		require.Equal(t, good.addr(), rec.Header().Get("lb-from"))
+	}
+	require.EqualValues(t, 2, good.hits.Load())
+}
+
+func TestRetry_NotForUnsafeMethods(t *testing.T) {
+	dead, good := newTestBackend(t), newTestBackend(t)
+	useBackends(t, dead, good)
+	dead.Close()
+	retries = newRetryBudget(0.2)
+
+	statuses := map[int]int{}
+	for i := 0; i < 2; i++ {
+		rec := httptest.NewRecorder()
+		handleRequest(rec, httptest.NewRequest(http.MethodPost, "/", strings.NewReader("x")))
+		statuses[rec.Code]++
+	}
+	require.Equal(t, map[int]int{http.StatusOK: 1, http.StatusBadGateway: 1}, statuses)
+}
+
+func TestRetry_ReplaysBufferedBody(t *testing.T) {
+	var bodies []string
+	good := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		data, _ := io.ReadAll(r.Body)
+		bodies = append(bodies, string(data))
+	}))
+	t.Cleanup(good.Close)
+	dead := newTestBackend(t)
+	dead.Close()
+
+	useBackends(t, dead)
+	backendStats[good.Listener.Addr().String()] = &BackendServer{Address: good.Listener.Addr().String(), Healthy: true}
+	retries = newRetryBudget(0.2)
+	retryMethods = parseMethods("GET,HEAD,PUT")
+	t.Cleanup(func() { retryMethods = parseMethods("GET,HEAD") })
+
+	for i := 0; i < 2; i++ {
+		rec := httptest.NewRecorder()
+		handleRequest(rec, httptest.NewRequest(http.MethodPut, "/", strings.NewReader("payload")))
+		require.Equal(t, http.StatusOK, rec.Code)
+	}
+	require.Equal(t, []string{"payload", "payload"}, bodies)
+}
+
+func TestBufferBody_TooLarge(t *testing.T) {
+	r := httptest.NewRequest(http.MethodPut, "/", strings.NewReader("0123456789"))
+	_, ok := bufferBody(r, 4)
+	require.False(t, ok)
+	data, err := io.ReadAll(r.Body)
+	require.NoError(t, err)
+	require.Equal(t, "0123456789", string(data), "body must be restored for a single attempt")
+}
+
+func TestRetryBudget(t *testing.T) {
+	b := newRetryBudget(0.5)
+	for i := 0; i < minRetryTokens; i++ {
+		require.True(t, b.withdraw())
+	}
+	require.False(t, b.withdraw(), "budget must be exhausted")
+
+	b.deposit()
+	require.False(t, b.withdraw())
+	b.deposit()
+	require.True(t, b.withdraw())
+}
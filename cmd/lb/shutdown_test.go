@@ -0,0 +1,100 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func freePort(t *testing.T) int {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer ln.Close()
+	return ln.Addr().(*net.TCPAddr).Port
+}
+
+func TestGracefulShutdown(t *testing.T) {
+	started, release := make(chan struct{}), make(chan struct{})
+	backend := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		close(started)
+		<-release
+		_, _ = rw.Write([]byte("done"))
+	}))
+	t.Cleanup(backend.Close)
+	addr := strings.TrimPrefix(backend.URL, "http://")
+	useBackends(t)
+	backendStats[addr] = &BackendServer{Address: addr, Healthy: true}
+
+	port := freePort(t)
+	frontend := newFrontend(port, nil)
+	frontend.Start()
+	url := fmt.Sprintf("http://127.0.0.1:%d/", port)
+	require.Eventually(t, func() bool {
+		conn, err := net.Dial("tcp", fmt.Sprintf("127.0.0.1:%d", port))
+		if err == nil {
+			conn.Close()
+		}
+		return err == nil
+	}, time.Second, 10*time.Millisecond)
+
+	type result struct {
+		body string
+		err  error
+	}
+	done := make(chan result, 1)
+	go func() {
+		resp, err := http.Get(url)
+		if err != nil {
+			done <- result{err: err}
+			return
+		}
+		defer resp.Body.Close()
+		body, err := io.ReadAll(resp.Body)
+		done <- result{string(body), err}
+	}()
+	<-started
+	require.EqualValues(t, 1, inFlight())
+
+	shutdown := make(chan error, 1)
+	go func() { shutdown <- frontend.Shutdown(5 * time.Second) }()
+
+	// Нові з'єднання вже не приймаються, а пересилання ще триває.
+	require.Eventually(t, func() bool {
+		_, err := http.Get(url)
+		return err != nil
+	}, time.Second, 10*time.Millisecond)
+	select {
+	case <-shutdown:
+		t.Fatal("shutdown must wait for the in-flight request")
+	default:
+	}
+
+	close(release)
+	res := <-done
+	require.NoError(t, res.err)
+	require.Equal(t, "done", res.body)
+	require.NoError(t, <-shutdown)
+}
+
+func TestDrainedStatus(t *testing.T) {
+	resetBackends(t)
+	applyConfig(Config{Backends: []BackendConfig{{Address: "a:80"}}})
+	server := backendStats["a:80"]
+	server.ActiveConns = 1
+	require.NoError(t, drainBackend("a:80"))
+
+	view := snapshotBackends()[0]
+	require.True(t, view.Draining)
+	require.False(t, view.Drained, "an in-flight request is still running")
+
+	server.ActiveConns = 0
+	require.True(t, snapshotBackends()[0].Drained)
+}
@@ -0,0 +1,74 @@
+package main
+
+import (
+	"math/rand"
+	"net/http"
+	"time"
+)
+
+// minSlowStartFactor — частка трафіку бекенда на самому початку розігріву,
+// щоб він усе ж отримував запити й розігрівався.
+const minSlowStartFactor = 0.1
+
+// slowStartRoll повертає випадкове число з [0, 1); тести підміняють його.
+var slowStartRoll = rand.Float64
+
+// slowStartFactor повертає частку звичайного трафіку, яку бекенд може отримати
+// в момент t: вона лінійно зростає від minSlowStartFactor до 1 протягом -slow-start.
+func slowStartFactor(server *BackendServer, t time.Time) float64 {
+	window := *slowStart
+	if window <= 0 || server.healthySince.IsZero() {
+		return 1
+	}
+	elapsed := t.Sub(server.healthySince)
+	if elapsed >= window {
+		return 1
+	}
+	return max(minSlowStartFactor, float64(elapsed)/float64(window))
+}
+
+// applySlowStart лишає серед кандидатів бекенд, що розігрівається, лише з імовірністю
+// slowStartFactor і один раз обирає бекенд стратегією s. Якщо не лишилося жодного
+// кандидата, обирає з усіх. Викликається під mu.
+func applySlowStart(r *http.Request, s Strategy, candidates []*BackendServer, t time.Time) *BackendServer {
+	admitted := make([]*BackendServer, 0, len(candidates))
+	for _, c := range candidates {
+		if f := slowStartFactor(c, t); f >= 1 || slowStartRoll() < f {
+			admitted = append(admitted, c)
+		}
+	}
+	if len(admitted) == 0 {
+		admitted = candidates
+	}
+	return s.Choose(r, admitted)
+}
+
+// warmUp починає розігрів бекенда, що повернувся в ротацію (активна перевірка,
+// кінець вилучення, замикання автомата чи ручне ввімкнення), і вирівнює його
+// лічильники трафіку з найменш навантаженим здоровим бекендом того ж пулу, щоб
+// least-traffic і least-bytes не віддали йому одразу всі запити. Викликається під mu.
+func warmUp(server *BackendServer, t time.Time) {
+	server.healthySince = t
+
+	var peers int
+	var minTraffic int64
+	var minBytes float64
+	for _, other := range backendStats {
+		if other == server || other.Pool != server.Pool || !other.Healthy {
+			continue
+		}
+		bytes := other.RecentBytes.at(t, *trafficHalfLife)
+		if peers == 0 || other.Traffic < minTraffic {
+			minTraffic = other.Traffic
+		}
+		if peers == 0 || bytes < minBytes {
+			minBytes = bytes
+		}
+		peers++
+	}
+	if peers == 0 {
+		return
+	}
+	server.Traffic = max(server.Traffic, minTraffic)
+	server.RecentBytes = decayingCounter{value: max(server.RecentBytes.at(t, *trafficHalfLife), minBytes), updated: t}
+}
@@ -0,0 +1,198 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+// useSlowStart задає вікно розігріву й детерміновану послідовність 0, 0.1, ..., 0.9 замість випадкових чисел.
+func useSlowStart(t *testing.T, window time.Duration) {
+	t.Helper()
+	prevWindow, prevRoll := *slowStart, slowStartRoll
+	*slowStart = window
+	var i int
+	slowStartRoll = func() float64 {
+		i++
+		return float64(i%10) / 10
+	}
+	t.Cleanup(func() {
+		*slowStart = prevWindow
+		slowStartRoll = prevRoll
+	})
+}
+
+type strategyFunc func(r *http.Request, candidates []*BackendServer) *BackendServer
+
+func (f strategyFunc) Choose(r *http.Request, candidates []*BackendServer) *BackendServer {
+	return f(r, candidates)
+}
+
+func TestSlowStartFactor(t *testing.T) {
+	advance := setNow(t)
+	useSlowStart(t, 100*time.Second)
+	server := &BackendServer{}
+	require.Equal(t, 1.0, slowStartFactor(server, now()), "a backend that never transitioned is not ramped")
+
+	server.healthySince = now()
+	require.Equal(t, minSlowStartFactor, slowStartFactor(server, now()))
+	advance(50 * time.Second)
+	require.InDelta(t, 0.5, slowStartFactor(server, now()), 1e-9)
+	advance(50 * time.Second)
+	require.Equal(t, 1.0, slowStartFactor(server, now()))
+}
+
+func TestSlowStart_RampsShare(t *testing.T) {
+	advance := setNow(t)
+	useSlowStart(t, 100*time.Second)
+	backendStats = make(map[string]*BackendServer)
+	for _, addr := range []string{"a", "b", "c"} {
+		backendStats[addr] = &BackendServer{Address: addr, Healthy: true}
+	}
+	// Стратегія, що завжди обирає c, якщо він серед кандидатів.
+	strategy = strategyFunc(func(_ *http.Request, candidates []*BackendServer) *BackendServer {
+		return candidates[len(candidates)-1]
+	})
+	t.Cleanup(func() { strategy = leastTraffic{} })
+	mu.Lock()
+	warmUp(backendStats["c"], now())
+	mu.Unlock()
+
+	share := func() int {
+		hits := 0
+		for i := 0; i < 100; i++ {
+			server, _ := chooseServer(httptest.NewRequest("GET", "/", nil), nil)
+			server.ActiveConns--
+			if server.Address == "c" {
+				hits++
+			}
+		}
+		return hits
+	}
+	require.Equal(t, 10, share())
+	advance(50 * time.Second)
+	require.Equal(t, 50, share())
+	advance(50 * time.Second)
+	require.Equal(t, 100, share())
+}
+
+func TestWarmUp_NormalizesTraffic(t *testing.T) {
+	setNow(t)
+	backendStats = map[string]*BackendServer{
+		"a": {Address: "a", Healthy: true, Traffic: 100},
+		"b": {Address: "b", Healthy: true, Traffic: 80},
+		"c": {Address: "c", Healthy: false, Traffic: 5},
+		"d": {Address: "d", Healthy: false, Traffic: 1},
+	}
+	backendStats["a"].RecentBytes.add(1000, now(), time.Minute)
+	backendStats["b"].RecentBytes.add(700, now(), time.Minute)
+
+	c := backendStats["c"]
+	c.Healthy = true
+	warmUp(c, now())
+	require.EqualValues(t, 80, c.Traffic)
+	require.InDelta(t, 700, c.RecentBytes.at(now(), time.Minute), 1e-9)
+	require.Equal(t, now(), c.healthySince)
+
+	// Новий бекенд не отримує всі запити least-traffic.
+	require.Equal(t, "b", getLeastTrafficServer().Address)
+}
+
+func TestWarmUp_PeersFromSamePool(t *testing.T) {
+	setNow(t)
+	backendStats = map[string]*BackendServer{
+		"a": {Address: "a", Healthy: true, Traffic: 2},
+		"b": {Address: "b", Healthy: true, Traffic: 100, Pool: "api"},
+		"c": {Address: "c", Healthy: true, Pool: "api"},
+	}
+	c := backendStats["c"]
+	warmUp(c, now())
+	require.EqualValues(t, 100, c.Traffic, "traffic of other pools does not matter")
+}
+
+func TestSlowStart_ChoosesOnce(t *testing.T) {
+	setNow(t)
+	useSlowStart(t, 100*time.Second)
+	backendStats = make(map[string]*BackendServer)
+	for _, addr := range []string{"a", "b", "c"} {
+		backendStats[addr] = &BackendServer{Address: addr, Healthy: true}
+	}
+	var calls int
+	strategy = strategyFunc(func(_ *http.Request, candidates []*BackendServer) *BackendServer {
+		calls++
+		return candidates[len(candidates)-1]
+	})
+	t.Cleanup(func() { strategy = leastTraffic{} })
+	mu.Lock()
+	warmUp(backendStats["c"], now())
+	mu.Unlock()
+
+	// Стан round-robin чи WRR просувається рівно раз на запит.
+	for i := 0; i < 20; i++ {
+		server, _ := chooseServer(httptest.NewRequest("GET", "/", nil), nil)
+		server.ActiveConns--
+	}
+	require.Equal(t, 20, calls)
+}
+
+func TestWarmUp_OnReadmission(t *testing.T) {
+	advance := setNow(t)
+	useSlowStart(t, 100*time.Second)
+	useCircuitBreaker(t, CircuitBreaker{FailureThreshold: 1, OpenTimeout: Duration(10 * time.Second), HalfOpenTrials: 1})
+	reset := func() *BackendServer {
+		backendStats = map[string]*BackendServer{
+			"a": {Address: "a", Healthy: true, Traffic: 100},
+			"b": {Address: "b", Healthy: true, Traffic: 5},
+		}
+		return backendStats["b"]
+	}
+
+	t.Run("outlier ejection ends", func(t *testing.T) {
+		b := reset()
+		b.outlier.EjectedUntil = now().Add(10 * time.Second)
+		mu.Lock()
+		defer mu.Unlock()
+		require.Len(t, healthyServers(""), 1)
+		advance(11 * time.Second)
+		require.Len(t, healthyServers(""), 2)
+		require.EqualValues(t, 100, b.Traffic)
+		require.Equal(t, now(), b.healthySince)
+
+		// Повторний вибір не починає розігрів заново.
+		advance(time.Second)
+		healthyServers("")
+		require.Equal(t, now().Add(-time.Second), b.healthySince)
+	})
+
+	t.Run("breaker closes", func(t *testing.T) {
+		b := reset()
+		mu.Lock()
+		defer mu.Unlock()
+		trackBreaker(b, forwardStats{StatusCode: http.StatusBadGateway}, nil)
+		require.Equal(t, circuitOpen, b.breaker.State)
+		advance(11 * time.Second)
+		require.True(t, b.breaker.available(now()))
+		trackBreaker(b, forwardStats{StatusCode: http.StatusOK}, nil)
+		require.Equal(t, circuitClosed, b.breaker.State)
+		require.EqualValues(t, 100, b.Traffic)
+		require.Equal(t, now(), b.healthySince)
+	})
+
+	t.Run("admin up", func(t *testing.T) {
+		b := reset()
+		b.Healthy = false
+		require.NoError(t, setOverride("b", overrideUp))
+		require.EqualValues(t, 100, b.Traffic)
+		require.Equal(t, now(), b.healthySince)
+
+		// Бекенд, що вже в ротації, не розігрівається знову.
+		advance(time.Second)
+		b.Healthy = true
+		require.NoError(t, setOverride("b", ""))
+		require.NoError(t, setOverride("b", overrideUp))
+		require.Equal(t, now().Add(-time.Second), b.healthySince)
+	})
+}
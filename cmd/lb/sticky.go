@@ -0,0 +1,89 @@
+package main
+
+import (
+	"fmt"
+	"hash/fnv"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+const defaultStickyCookie = "lb-session"
+
+// stickiness закріплює клієнта за бекендом: балансувальник видає ідентифікатор
+// бекенда в cookie або заголовку відповіді, а клієнт повертає його в наступних запитах.
+type stickiness struct {
+	cookie bool
+	name   string
+}
+
+// sticky — налаштування з прапорця -sticky; nil вимикає закріплення.
+var sticky *stickiness
+
+// parseSticky розбирає значення -sticky: "", "cookie", "cookie:<name>" або "header:<name>".
+func parseSticky(spec string) (*stickiness, error) {
+	kind, name, _ := strings.Cut(spec, ":")
+	switch {
+	case spec == "":
+		return nil, nil
+	case kind == "cookie" && name == "":
+		return &stickiness{cookie: true, name: defaultStickyCookie}, nil
+	case kind == "cookie":
+		return &stickiness{cookie: true, name: name}, nil
+	case kind == "header" && name != "":
+		return &stickiness{name: http.CanonicalHeaderKey(name)}, nil
+	}
+	return nil, fmt.Errorf("invalid session affinity %q", spec)
+}
+
+// backendID — непрозорий ідентифікатор бекенда, щоб не розкривати клієнтам його адресу.
+func backendID(addr string) string {
+	h := fnv.New64a()
+	_, _ = h.Write([]byte(addr))
+	return strconv.FormatUint(h.Sum64(), 36)
+}
+
+func (s *stickiness) pinned(r *http.Request) string {
+	if !s.cookie {
+		return r.Header.Get(s.name)
+	}
+	if c, err := r.Cookie(s.name); err == nil {
+		return c.Value
+	}
+	return ""
+}
+
+// server повертає бекенд, за яким закріплено клієнта, якщо той є серед кандидатів.
+// Інакше повертає nil, і бекенд обирає стратегія. Викликається під mu.
+func (s *stickiness) server(r *http.Request, candidates []*BackendServer) *BackendServer {
+	id := s.pinned(r)
+	if id == "" {
+		return nil
+	}
+	for _, server := range candidates {
+		if backendID(server.Address) == id {
+			return server
+		}
+	}
+	return nil
+}
+
+// pin повідомляє клієнту, за яким бекендом його закріплено.
+func (s *stickiness) pin(rw http.ResponseWriter, r *http.Request, server *BackendServer) {
+	id := backendID(server.Address)
+	if !s.cookie {
+		rw.Header().Set(s.name, id)
+		return
+	}
+	if s.pinned(r) == id {
+		return
+	}
+	rw.Header().Set("Set-Cookie", (&http.Cookie{
+		Name:     s.name,
+		Value:    id,
+		Path:     "/",
+		HttpOnly: true,
+		Secure:   r.TLS != nil,
+		SameSite: http.SameSiteLaxMode,
+	}).String())
+}
@@ -0,0 +1,99 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func useSticky(t *testing.T, spec string) {
+	t.Helper()
+	var err error
+	sticky, err = parseSticky(spec)
+	require.NoError(t, err)
+	*traceEnabled = true
+	t.Cleanup(func() {
+		sticky = nil
+		*traceEnabled = false
+	})
+}
+
+func TestSticky_Cookie(t *testing.T) {
+	backends := []*testBackend{newTestBackend(t), newTestBackend(t), newTestBackend(t)}
+	useBackends(t, backends...)
+	useSticky(t, "cookie")
+
+	rec := httptest.NewRecorder()
+	handleRequest(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+	cookies := rec.Result().Cookies()
+	require.Len(t, cookies, 1)
+	session := cookies[0]
+	require.Equal(t, defaultStickyCookie, session.Name)
+	pinned := rec.Header().Get("lb-from")
+
+	for i := 0; i < 30; i++ {
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		req.AddCookie(session)
+		rec := httptest.NewRecorder()
+		handleRequest(rec, req)
+		require.Equal(t, pinned, rec.Header().Get("lb-from"))
+		require.Empty(t, rec.Result().Cookies(), "cookie must not be reissued for the same backend")
+	}
+
+	// Закріплений бекенд став нездоровим — запит іде деінде з новою cookie.
+	backendStats[pinned].Healthy = false
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.AddCookie(session)
+	rec = httptest.NewRecorder()
+	handleRequest(rec, req)
+	require.Equal(t, http.StatusOK, rec.Code)
+	require.NotEqual(t, pinned, rec.Header().Get("lb-from"))
+	require.Len(t, rec.Result().Cookies(), 1)
+	require.NotEqual(t, session.Value, rec.Result().Cookies()[0].Value)
+}
+
+func TestSticky_Header(t *testing.T) {
+	backends := []*testBackend{newTestBackend(t), newTestBackend(t), newTestBackend(t)}
+	useBackends(t, backends...)
+	useSticky(t, "header:x-lb-session")
+
+	rec := httptest.NewRecorder()
+	handleRequest(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+	session := rec.Header().Get("X-Lb-Session")
+	require.NotEmpty(t, session)
+	pinned := rec.Header().Get("lb-from")
+
+	for i := 0; i < 30; i++ {
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		req.Header.Set("X-Lb-Session", session)
+		rec := httptest.NewRecorder()
+		handleRequest(rec, req)
+		require.Equal(t, pinned, rec.Header().Get("lb-from"))
+	}
+
+	// Без заголовка працює звичайна стратегія.
+	seen := make(map[string]bool)
+	for i := 0; i < 3; i++ {
+		rec := httptest.NewRecorder()
+		handleRequest(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+		seen[rec.Header().Get("lb-from")] = true
+	}
+	require.Len(t, seen, 3)
+}
+
+func TestParseSticky(t *testing.T) {
+	s, err := parseSticky("")
+	require.NoError(t, err)
+	require.Nil(t, s)
+
+	s, err = parseSticky("cookie:sid")
+	require.NoError(t, err)
+	require.Equal(t, &stickiness{cookie: true, name: "sid"}, s)
+
+	for _, spec := range []string{"header", "header:", "ip"} {
+		_, err := parseSticky(spec)
+		require.Error(t, err, spec)
+	}
+}
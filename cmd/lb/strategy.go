@@ -0,0 +1,250 @@
+package main
+
+import (
+	"fmt"
+	"math/rand"
+	"net"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/roman-mazur/architecture-practice-4-template/hashring"
+)
+
+// Strategy обирає бекенд для запиту серед здорових кандидатів.
+// Реалізації не потокобезпечні: Choose викликається під mu.
+type Strategy interface {
+	Choose(r *http.Request, candidates []*BackendServer) *BackendServer
+}
+
+const (
+	strategyLeastTraffic       = "least-traffic"
+	strategyRoundRobin         = "round-robin"
+	strategyWeightedRoundRobin = "weighted-round-robin"
+	strategyLeastConnections   = "least-connections"
+	strategyRandomTwo          = "random-two"
+	strategyConsistentHash     = "consistent-hash"
+	strategyLeastBytes         = "least-bytes"
+)
+
+var strategyFactories = map[string]func(hashOn string) (Strategy, error){
+	strategyLeastTraffic:       func(string) (Strategy, error) { return leastTraffic{}, nil },
+	strategyRoundRobin:         func(string) (Strategy, error) { return &roundRobin{}, nil },
+	strategyWeightedRoundRobin: func(string) (Strategy, error) { return newWeightedRoundRobin(), nil },
+	strategyLeastConnections:   func(string) (Strategy, error) { return leastConnections{}, nil },
+	strategyLeastBytes:         func(string) (Strategy, error) { return leastBytes{}, nil },
+	strategyRandomTwo: func(string) (Strategy, error) {
+		return newRandomTwo(rand.New(rand.NewSource(time.Now().UnixNano()))), nil
+	},
+	strategyConsistentHash: func(hashOn string) (Strategy, error) {
+		attr, err := parseHashAttribute(hashOn)
+		if err != nil {
+			return nil, err
+		}
+		return newConsistentHash(attr), nil
+	},
+}
+
+func strategyNames() []string {
+	names := make([]string, 0, len(strategyFactories))
+	for name := range strategyFactories {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// newStrategy створює стратегію за назвою з прапорця -strategy.
+func newStrategy(name, hashOn string) (Strategy, error) {
+	factory, ok := strategyFactories[name]
+	if !ok {
+		return nil, fmt.Errorf("unknown strategy %q, expected one of: %s", name, strings.Join(strategyNames(), ", "))
+	}
+	return factory(hashOn)
+}
+
+// leastTraffic — найменша кількість успішно обслужених запитів.
+type leastTraffic struct{}
+
+func (leastTraffic) Choose(_ *http.Request, candidates []*BackendServer) *BackendServer {
+	var selected *BackendServer
+	for _, server := range candidates {
+		if selected == nil || server.Traffic < selected.Traffic {
+			selected = server
+		}
+	}
+	return selected
+}
+
+type roundRobin struct {
+	next int
+}
+
+func (s *roundRobin) Choose(_ *http.Request, candidates []*BackendServer) *BackendServer {
+	if len(candidates) == 0 {
+		return nil
+	}
+	server := candidates[s.next%len(candidates)]
+	s.next = (s.next + 1) % len(candidates)
+	return server
+}
+
+func weightOf(server *BackendServer) int {
+	if server.Weight <= 0 {
+		return 1
+	}
+	return server.Weight
+}
+
+// weightedRoundRobin — згладжений зважений round-robin (як у nginx):
+// бекенди з більшою вагою обираються частіше, але не поспіль.
+type weightedRoundRobin struct {
+	current map[string]int
+}
+
+func newWeightedRoundRobin() *weightedRoundRobin {
+	return &weightedRoundRobin{current: make(map[string]int)}
+}
+
+func (s *weightedRoundRobin) Choose(_ *http.Request, candidates []*BackendServer) *BackendServer {
+	var selected *BackendServer
+	total := 0
+	for _, server := range candidates {
+		w := weightOf(server)
+		total += w
+		s.current[server.Address] += w
+		if selected == nil || s.current[server.Address] > s.current[selected.Address] {
+			selected = server
+		}
+	}
+	if selected != nil {
+		s.current[selected.Address] -= total
+	}
+	return selected
+}
+
+// leastConnections — найменше запитів в обробці; при рівності — менший трафік.
+type leastConnections struct{}
+
+func (leastConnections) Choose(_ *http.Request, candidates []*BackendServer) *BackendServer {
+	var selected *BackendServer
+	for _, server := range candidates {
+		if selected == nil || server.ActiveConns < selected.ActiveConns ||
+			(server.ActiveConns == selected.ActiveConns && server.Traffic < selected.Traffic) {
+			selected = server
+		}
+	}
+	return selected
+}
+
+// leastBytes — найменше байтів за згасаючим вікном.
+type leastBytes struct{}
+
+func (leastBytes) Choose(_ *http.Request, candidates []*BackendServer) *BackendServer {
+	t := now()
+	var selected *BackendServer
+	var selectedBytes float64
+	for _, server := range candidates {
+		b := server.RecentBytes.at(t, *trafficHalfLife)
+		if selected == nil || b < selectedBytes {
+			selected, selectedBytes = server, b
+		}
+	}
+	return selected
+}
+
+// randomTwo — "power of two choices": з двох випадкових бекендів обирає менш завантажений.
+type randomTwo struct {
+	rnd *rand.Rand
+}
+
+func newRandomTwo(rnd *rand.Rand) *randomTwo {
+	return &randomTwo{rnd: rnd}
+}
+
+func (s *randomTwo) Choose(_ *http.Request, candidates []*BackendServer) *BackendServer {
+	switch len(candidates) {
+	case 0:
+		return nil
+	case 1:
+		return candidates[0]
+	}
+	i := s.rnd.Intn(len(candidates))
+	j := s.rnd.Intn(len(candidates) - 1)
+	if j >= i {
+		j++
+	}
+	a, b := candidates[i], candidates[j]
+	if b.ActiveConns < a.ActiveConns {
+		return b
+	}
+	return a
+}
+
+// hashAttribute витягує з запиту значення, за яким працює consistent-hash.
+type hashAttribute func(r *http.Request) string
+
+func parseHashAttribute(spec string) (hashAttribute, error) {
+	kind, name, _ := strings.Cut(spec, ":")
+	switch {
+	case kind == "ip":
+		return clientIP, nil
+	case kind == "path":
+		return func(r *http.Request) string { return r.URL.Path }, nil
+	case kind == "header" && name != "":
+		return func(r *http.Request) string { return r.Header.Get(name) }, nil
+	case kind == "cookie" && name != "":
+		return func(r *http.Request) string {
+			if c, err := r.Cookie(name); err == nil {
+				return c.Value
+			}
+			return ""
+		}, nil
+	case kind == "query" && name != "":
+		return func(r *http.Request) string { return r.URL.Query().Get(name) }, nil
+	}
+	return nil, fmt.Errorf("invalid hash attribute %q", spec)
+}
+
+func clientIP(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
+
+// consistentHash закріплює однакові значення атрибута за одним бекендом;
+// при зміні набору здорових бекендів переміщується лише частина ключів.
+type consistentHash struct {
+	attr hashAttribute
+	ring *hashring.Ring
+	set  string // адреси, з яких побудовано ring
+}
+
+func newConsistentHash(attr hashAttribute) *consistentHash {
+	return &consistentHash{attr: attr}
+}
+
+func (s *consistentHash) Choose(r *http.Request, candidates []*BackendServer) *BackendServer {
+	if len(candidates) == 0 {
+		return nil
+	}
+	addrs := make([]string, len(candidates))
+	byAddr := make(map[string]*BackendServer, len(candidates))
+	for i, server := range candidates {
+		addrs[i] = server.Address
+		byAddr[server.Address] = server
+	}
+	if set := strings.Join(addrs, ","); s.ring == nil || set != s.set {
+		s.ring = hashring.New(hashring.DefaultReplicas, addrs...)
+		s.set = set
+	}
+
+	var key string
+	if r != nil {
+		key = s.attr(r)
+	}
+	return byAddr[s.ring.Get(key)]
+}
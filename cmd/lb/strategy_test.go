@@ -0,0 +1,187 @@
+package main
+
+import (
+	"math/rand"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func testBackends(n int) []*BackendServer {
+	res := make([]*BackendServer, n)
+	for i := range res {
+		res[i] = &BackendServer{Address: "srv" + strconv.Itoa(i+1), Healthy: true}
+	}
+	return res
+}
+
+func TestNewStrategy(t *testing.T) {
+	for _, name := range strategyNames() {
+		s, err := newStrategy(name, "ip")
+		require.NoError(t, err, name)
+		require.NotNil(t, s, name)
+	}
+
+	_, err := newStrategy("unknown", "ip")
+	require.Error(t, err)
+	_, err = newStrategy(strategyConsistentHash, "header:")
+	require.Error(t, err)
+}
+
+func TestStrategies_NoCandidates(t *testing.T) {
+	for _, name := range strategyNames() {
+		s, err := newStrategy(name, "ip")
+		require.NoError(t, err)
+		require.Nil(t, s.Choose(httptest.NewRequest("GET", "/", nil), nil), name)
+	}
+}
+
+func TestRoundRobin(t *testing.T) {
+	backends := testBackends(3)
+	s := &roundRobin{}
+
+	var got []string
+	for i := 0; i < 6; i++ {
+		got = append(got, s.Choose(nil, backends).Address)
+	}
+	require.Equal(t, []string{"srv1", "srv2", "srv3", "srv1", "srv2", "srv3"}, got)
+
+	// Набір кандидатів зменшився — індекс не виходить за межі.
+	require.NotNil(t, s.Choose(nil, backends[:1]))
+}
+
+func TestWeightedRoundRobin(t *testing.T) {
+	backends := testBackends(3)
+	backends[0].Weight = 5
+	backends[1].Weight = 1
+	backends[2].Weight = 0 // трактується як 1
+	s := newWeightedRoundRobin()
+
+	counts := make(map[string]int)
+	var sequence []string
+	for i := 0; i < 70; i++ {
+		addr := s.Choose(nil, backends).Address
+		counts[addr]++
+		sequence = append(sequence, addr)
+	}
+	require.Equal(t, map[string]int{"srv1": 50, "srv2": 10, "srv3": 10}, counts)
+
+	// Згладжений алгоритм не віддає важкому бекенду всі 5 запитів поспіль.
+	run, maxRun := 0, 0
+	for i, addr := range sequence {
+		if i > 0 && addr == sequence[i-1] {
+			run++
+		} else {
+			run = 1
+		}
+		maxRun = max(maxRun, run)
+	}
+	require.Less(t, maxRun, 5)
+}
+
+func TestLeastConnections(t *testing.T) {
+	backends := testBackends(3)
+	backends[0].ActiveConns = 3
+	backends[1].ActiveConns = 1
+	backends[2].ActiveConns = 1
+	backends[1].Traffic = 10
+	backends[2].Traffic = 5
+
+	require.Equal(t, "srv3", leastConnections{}.Choose(nil, backends).Address)
+
+	backends[2].ActiveConns = 4
+	require.Equal(t, "srv2", leastConnections{}.Choose(nil, backends).Address)
+}
+
+func TestRandomTwo(t *testing.T) {
+	backends := testBackends(3)
+	backends[0].ActiveConns = 10
+	backends[1].ActiveConns = 0
+	backends[2].ActiveConns = 5
+	s := newRandomTwo(rand.New(rand.NewSource(1)))
+
+	counts := make(map[string]int)
+	for i := 0; i < 300; i++ {
+		counts[s.Choose(nil, backends).Address]++
+	}
+	// Найзавантаженіший бекенд ніколи не виграє порівняння.
+	require.Zero(t, counts["srv1"])
+	require.Greater(t, counts["srv2"], counts["srv3"])
+
+	require.Equal(t, "srv1", s.Choose(nil, backends[:1]).Address)
+}
+
+func TestConsistentHash(t *testing.T) {
+	attr, err := parseHashAttribute("header:X-User")
+	require.NoError(t, err)
+	s := newConsistentHash(attr)
+	backends := testBackends(3)
+
+	request := func(user string) *http.Request {
+		r := httptest.NewRequest("GET", "/", nil)
+		r.Header.Set("X-User", user)
+		return r
+	}
+
+	owners := make(map[string]string)
+	used := make(map[string]bool)
+	for i := 0; i < 100; i++ {
+		user := "user-" + strconv.Itoa(i)
+		owner := s.Choose(request(user), backends).Address
+		owners[user] = owner
+		used[owner] = true
+		require.Equal(t, owner, s.Choose(request(user), backends).Address)
+	}
+	require.Len(t, used, 3)
+
+	// srv2 став нездоровим — переміщуються лише його ключі.
+	remaining := []*BackendServer{backends[0], backends[2]}
+	for user, owner := range owners {
+		now := s.Choose(request(user), remaining).Address
+		if owner != "srv2" {
+			require.Equal(t, owner, now, user)
+		} else {
+			require.NotEqual(t, "srv2", now)
+		}
+	}
+}
+
+func TestParseHashAttribute(t *testing.T) {
+	r := httptest.NewRequest("GET", "/some/path?key=q", nil)
+	r.RemoteAddr = "10.0.0.1:1234"
+	r.Header.Set("X-Id", "h")
+	r.AddCookie(&http.Cookie{Name: "sid", Value: "c"})
+
+	for spec, expected := range map[string]string{
+		"ip":          "10.0.0.1",
+		"path":        "/some/path",
+		"header:X-Id": "h",
+		"cookie:sid":  "c",
+		"query:key":   "q",
+	} {
+		attr, err := parseHashAttribute(spec)
+		require.NoError(t, err, spec)
+		require.Equal(t, expected, attr(r), spec)
+	}
+
+	_, err := parseHashAttribute("body")
+	require.Error(t, err)
+}
+
+func TestChooseServer_TracksActiveConnections(t *testing.T) {
+	backendStats = map[string]*BackendServer{
+		"srv1": {Address: "srv1", Healthy: true},
+		"srv2": {Address: "srv2", Healthy: true},
+	}
+	strategy = leastConnections{}
+	t.Cleanup(func() { strategy = leastTraffic{} })
+
+	first, _ := chooseServer(httptest.NewRequest("GET", "/", nil), nil)
+	second, _ := chooseServer(httptest.NewRequest("GET", "/", nil), nil)
+	require.NotEqual(t, first.Address, second.Address)
+	require.EqualValues(t, 1, first.ActiveConns)
+	require.EqualValues(t, 1, second.ActiveConns)
+}
@@ -0,0 +1,88 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// deadlineHeader передає бекенду, скільки мілісекунд лишилося до завершення
+// запиту. Якщо його надіслав клієнт (наприклад, інший проксі), балансувальник
+// не чекатиме довше.
+const deadlineHeader = "lb-timeout-ms"
+
+// RouteTimeout задає тайм-аут для запитів, шлях яких починається з PathPrefix.
+// Якщо підходить кілька правил, діє правило з найдовшим префіксом.
+type RouteTimeout struct {
+	PathPrefix string   `json:"pathPrefix"`
+	Methods    []string `json:"methods,omitempty"` // порожній список означає будь-який метод
+	Timeout    Duration `json:"timeout"`
+}
+
+// routeTimeouts — правила з файлу конфігурації. Захищено mu.
+var routeTimeouts []RouteTimeout
+
+func (rt RouteTimeout) validate() error {
+	if !strings.HasPrefix(rt.PathPrefix, "/") {
+		return fmt.Errorf("timeout path prefix %q must start with /", rt.PathPrefix)
+	}
+	if rt.Timeout <= 0 {
+		return fmt.Errorf("timeout for %s must be positive", rt.PathPrefix)
+	}
+	return nil
+}
+
+func (rt RouteTimeout) matches(r *http.Request) bool {
+	return matchesPathAndMethod(r, rt.PathPrefix, rt.Methods)
+}
+
+func matchesPathAndMethod(r *http.Request, prefix string, methods []string) bool {
+	if !strings.HasPrefix(r.URL.Path, prefix) {
+		return false
+	}
+	return len(methods) == 0 || slices.ContainsFunc(methods, func(m string) bool {
+		return strings.EqualFold(m, r.Method)
+	})
+}
+
+// timeoutFor повертає тайм-аут запиту: з найточнішого правила маршруту або
+// -timeout-sec, але не більше за залишок, переданий клієнтом у deadlineHeader. Викликається під mu.
+func timeoutFor(r *http.Request) time.Duration {
+	res, best := timeout, -1
+	for _, rt := range routeTimeouts {
+		if len(rt.PathPrefix) > best && rt.matches(r) {
+			res, best = time.Duration(rt.Timeout), len(rt.PathPrefix)
+		}
+	}
+	if ms, err := strconv.ParseInt(r.Header.Get(deadlineHeader), 10, 64); err == nil && ms > 0 {
+		res = min(res, time.Duration(ms)*time.Millisecond)
+	}
+	return res
+}
+
+// setDeadlineHeader записує в запит до бекенда залишок часу з його контексту.
+func setDeadlineHeader(out *http.Request) {
+	deadline, ok := out.Context().Deadline()
+	if !ok {
+		out.Header.Del(deadlineHeader)
+		return
+	}
+	ms := max(time.Until(deadline).Milliseconds(), 1)
+	out.Header.Set(deadlineHeader, strconv.FormatInt(ms, 10))
+}
+
+// errorStatus обирає статус відповіді клієнту, коли бекенд не відповів:
+// 504, якщо вичерпано час, і 502 для інших збоїв.
+func errorStatus(err error) int {
+	var netErr net.Error
+	if errors.Is(err, context.DeadlineExceeded) || errors.As(err, &netErr) && netErr.Timeout() {
+		return http.StatusGatewayTimeout
+	}
+	return http.StatusBadGateway
+}
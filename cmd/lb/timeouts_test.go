@@ -0,0 +1,136 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func useRouteTimeouts(t *testing.T, rules ...RouteTimeout) {
+	t.Helper()
+	routeTimeouts = rules
+	t.Cleanup(func() { routeTimeouts = nil })
+}
+
+func TestTimeoutFor(t *testing.T) {
+	timeout = 3 * time.Second
+	useRouteTimeouts(t,
+		RouteTimeout{PathPrefix: "/api/slow", Methods: []string{"GET"}, Timeout: Duration(30 * time.Second)},
+		RouteTimeout{PathPrefix: "/api", Timeout: Duration(5 * time.Second)},
+	)
+
+	for _, tc := range []struct {
+		method, path, header string
+		want                 time.Duration
+	}{
+		{"GET", "/other", "", 3 * time.Second},
+		{"GET", "/api/v1", "", 5 * time.Second},
+		{"GET", "/api/slow/report", "", 30 * time.Second},
+		{"POST", "/api/slow/report", "", 5 * time.Second},
+		{"GET", "/api/slow/report", "1500", 1500 * time.Millisecond},
+		{"GET", "/api/v1", "60000", 5 * time.Second},
+		{"GET", "/api/v1", "garbage", 5 * time.Second},
+	} {
+		r := httptest.NewRequest(tc.method, tc.path, nil)
+		if tc.header != "" {
+			r.Header.Set(deadlineHeader, tc.header)
+		}
+		require.Equal(t, tc.want, timeoutFor(r), "%s %s %s", tc.method, tc.path, tc.header)
+	}
+}
+
+func TestDeadlinePropagation(t *testing.T) {
+	var got string
+	frontend := useProxyBackend(t, func(rw http.ResponseWriter, r *http.Request) {
+		got = r.Header.Get(deadlineHeader)
+	})
+	useRouteTimeouts(t, RouteTimeout{PathPrefix: "/api", Timeout: Duration(2 * time.Second)})
+
+	resp, err := http.Get(frontend.URL + "/api/data")
+	require.NoError(t, err)
+	resp.Body.Close()
+	ms, err := strconv.Atoi(got)
+	require.NoError(t, err)
+	require.Greater(t, ms, 0)
+	require.LessOrEqual(t, ms, 2000)
+}
+
+func TestErrorStatuses(t *testing.T) {
+	t.Run("gateway timeout", func(t *testing.T) {
+		release := make(chan struct{})
+		useProxyBackend(t, func(rw http.ResponseWriter, r *http.Request) {
+			<-release
+		})
+		defer close(release)
+		useRouteTimeouts(t, RouteTimeout{PathPrefix: "/", Timeout: Duration(50 * time.Millisecond)})
+
+		rec := httptest.NewRecorder()
+		handleRequest(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+		require.Equal(t, http.StatusGatewayTimeout, rec.Code)
+	})
+
+	t.Run("bad gateway", func(t *testing.T) {
+		useProxyBackend(t, func(rw http.ResponseWriter, r *http.Request) {
+			// Бекенд обриває з'єднання, не відповівши.
+			conn, _, err := http.NewResponseController(rw).Hijack()
+			if err == nil {
+				conn.Close()
+			}
+		})
+
+		rec := httptest.NewRecorder()
+		handleRequest(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+		require.Equal(t, http.StatusBadGateway, rec.Code)
+	})
+
+	t.Run("service unavailable", func(t *testing.T) {
+		useBackends(t)
+		rec := httptest.NewRecorder()
+		handleRequest(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+		require.Equal(t, http.StatusServiceUnavailable, rec.Code)
+	})
+}
+
+func TestTimeout_SlowRequestBody(t *testing.T) {
+	backend := newTestBackend(t)
+	useBackends(t, backend)
+	useLimits(t, Limits{MaxInFlight: 1})
+	timeout = 200 * time.Millisecond
+
+	port := freePort(t)
+	frontend := newFrontend(port, nil)
+	frontend.Start()
+	t.Cleanup(func() { _ = frontend.Shutdown(time.Second) })
+	addr := fmt.Sprintf("127.0.0.1:%d", port)
+	require.Eventually(t, func() bool {
+		conn, err := net.Dial("tcp", addr)
+		if err == nil {
+			conn.Close()
+		}
+		return err == nil
+	}, time.Second, 10*time.Millisecond)
+
+	// Клієнт надсилає лише частину тіла, яке балансувальник буферизує для повторів.
+	conn, err := net.Dial("tcp", addr)
+	require.NoError(t, err)
+	t.Cleanup(func() { conn.Close() })
+	_, err = conn.Write([]byte("GET / HTTP/1.1\r\nHost: lb\r\nContent-Length: 10\r\n\r\na"))
+	require.NoError(t, err)
+	require.NoError(t, conn.SetReadDeadline(time.Now().Add(2*time.Second)))
+	resp, err := http.ReadResponse(bufio.NewReader(conn), nil)
+	require.NoError(t, err, "the request must not wait for the body forever")
+	resp.Body.Close()
+
+	// Місце серед MaxInFlight звільнилося.
+	resp, err = http.Get("http://" + addr + "/")
+	require.NoError(t, err)
+	resp.Body.Close()
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+}
@@ -0,0 +1,142 @@
+package main
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+)
+
+// certStore зберігає сертифікати з файлів і перечитує їх без перезапуску.
+// Сертифікат для з'єднання обирається за SNI; перший у списку — типовий.
+type certStore struct {
+	certFiles, keyFiles []string
+
+	mu     sync.RWMutex
+	certs  []*tls.Certificate
+	byName map[string]*tls.Certificate
+}
+
+func newCertStore(certFiles, keyFiles []string) (*certStore, error) {
+	if len(certFiles) == 0 || len(certFiles) != len(keyFiles) {
+		return nil, fmt.Errorf("got %d certificates and %d keys", len(certFiles), len(keyFiles))
+	}
+	s := &certStore{certFiles: certFiles, keyFiles: keyFiles}
+	return s, s.reload()
+}
+
+// reload перечитує файли. Якщо хоч один не завантажився, лишаються попередні сертифікати.
+func (s *certStore) reload() error {
+	certs := make([]*tls.Certificate, 0, len(s.certFiles))
+	byName := make(map[string]*tls.Certificate)
+	for i, certFile := range s.certFiles {
+		cert, err := tls.LoadX509KeyPair(certFile, s.keyFiles[i])
+		if err != nil {
+			return fmt.Errorf("load %s: %w", certFile, err)
+		}
+		certs = append(certs, &cert)
+		names := cert.Leaf.DNSNames
+		if len(names) == 0 && cert.Leaf.Subject.CommonName != "" {
+			names = []string{cert.Leaf.Subject.CommonName}
+		}
+		for _, name := range names {
+			name = strings.ToLower(name)
+			if _, ok := byName[name]; !ok {
+				byName[name] = &cert
+			}
+		}
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.certs, s.byName = certs, byName
+	return nil
+}
+
+// GetCertificate обирає сертифікат за іменем сервера: точний збіг, потім
+// wildcard-сертифікат, інакше типовий.
+func (s *certStore) GetCertificate(hello *tls.ClientHelloInfo) (*tls.Certificate, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	name := strings.ToLower(strings.TrimSuffix(hello.ServerName, "."))
+	if cert, ok := s.byName[name]; ok {
+		return cert, nil
+	}
+	if i := strings.IndexByte(name, '.'); i > 0 {
+		if cert, ok := s.byName["*"+name[i:]]; ok {
+			return cert, nil
+		}
+	}
+	return s.certs[0], nil
+}
+
+// GetClientCertificate повертає клієнтський сертифікат для з'єднань з бекендами.
+func (s *certStore) GetClientCertificate(*tls.CertificateRequestInfo) (*tls.Certificate, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.certs[0], nil
+}
+
+func splitList(list string) []string {
+	var res []string
+	for _, v := range strings.Split(list, ",") {
+		if v = strings.TrimSpace(v); v != "" {
+			res = append(res, v)
+		}
+	}
+	return res
+}
+
+// frontendTLS повертає налаштування TLS для клієнтських з'єднань або nil,
+// якщо сертифікати не задано.
+func frontendTLS(certs, keys string) (*tls.Config, *certStore, error) {
+	if certs == "" && keys == "" {
+		return nil, nil, nil
+	}
+	store, err := newCertStore(splitList(certs), splitList(keys))
+	if err != nil {
+		return nil, nil, err
+	}
+	return &tls.Config{
+		MinVersion:     tls.VersionTLS12,
+		GetCertificate: store.GetCertificate,
+	}, store, nil
+}
+
+// backendTLS — налаштування TLS для з'єднань з бекендами при -https; nil означає системні.
+var backendTLS *tls.Config
+
+// newBackendTLS будує налаштування з власним CA та клієнтським сертифікатом для mTLS.
+func newBackendTLS(caFile, certFile, keyFile string) (*tls.Config, *certStore, error) {
+	if caFile == "" && certFile == "" && keyFile == "" {
+		return nil, nil, nil
+	}
+	cfg := &tls.Config{MinVersion: tls.VersionTLS12}
+	if caFile != "" {
+		pem, err := os.ReadFile(caFile)
+		if err != nil {
+			return nil, nil, err
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, nil, fmt.Errorf("no certificates found in %s", caFile)
+		}
+		cfg.RootCAs = pool
+	}
+
+	var store *certStore
+	if certFile != "" || keyFile != "" {
+		if certFile == "" || keyFile == "" {
+			return nil, nil, errors.New("backend client certificate and key must be set together")
+		}
+		var err error
+		if store, err = newCertStore([]string{certFile}, []string{keyFile}); err != nil {
+			return nil, nil, err
+		}
+		cfg.GetClientCertificate = store.GetClientCertificate
+	}
+	return cfg, store, nil
+}
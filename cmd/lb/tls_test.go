@@ -0,0 +1,219 @@
+package main
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+// testCA випускає сертифікати для тестів.
+type testCA struct {
+	cert   *x509.Certificate
+	key    *ecdsa.PrivateKey
+	serial int64
+	dir    string
+}
+
+func newTestCA(t *testing.T) *testCA {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	tmpl := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "test CA"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  true,
+		KeyUsage:              x509.KeyUsageCertSign,
+		BasicConstraintsValid: true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	require.NoError(t, err)
+	cert, err := x509.ParseCertificate(der)
+	require.NoError(t, err)
+
+	ca := &testCA{cert: cert, key: key, serial: 1, dir: t.TempDir()}
+	writePEM(t, ca.file("ca.pem"), "CERTIFICATE", der)
+	return ca
+}
+
+func (ca *testCA) file(name string) string {
+	return filepath.Join(ca.dir, name)
+}
+
+func (ca *testCA) pool() *x509.CertPool {
+	pool := x509.NewCertPool()
+	pool.AddCert(ca.cert)
+	return pool
+}
+
+// issue випускає сертифікат для hosts (DNS-імена або IP) і записує його у файли name.pem і name.key.
+func (ca *testCA) issue(t *testing.T, name string, hosts ...string) (certFile, keyFile string) {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	ca.serial++
+	tmpl := &x509.Certificate{
+		SerialNumber: big.NewInt(ca.serial),
+		Subject:      pkix.Name{CommonName: name},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth},
+	}
+	for _, h := range hosts {
+		if ip := net.ParseIP(h); ip != nil {
+			tmpl.IPAddresses = append(tmpl.IPAddresses, ip)
+		} else {
+			tmpl.DNSNames = append(tmpl.DNSNames, h)
+		}
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, ca.cert, &key.PublicKey, ca.key)
+	require.NoError(t, err)
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	require.NoError(t, err)
+
+	certFile, keyFile = ca.file(name+".pem"), ca.file(name+".key")
+	writePEM(t, certFile, "CERTIFICATE", der)
+	writePEM(t, keyFile, "EC PRIVATE KEY", keyDER)
+	return certFile, keyFile
+}
+
+func writePEM(t *testing.T, path, kind string, der []byte) {
+	t.Helper()
+	require.NoError(t, os.WriteFile(path, pem.EncodeToMemory(&pem.Block{Type: kind, Bytes: der}), 0o600))
+}
+
+func TestCertStore_SNI(t *testing.T) {
+	ca := newTestCA(t)
+	aCert, aKey := ca.issue(t, "a", "a.example.com")
+	bCert, bKey := ca.issue(t, "b", "*.b.example.com")
+	store, err := newCertStore([]string{aCert, bCert}, []string{aKey, bKey})
+	require.NoError(t, err)
+
+	for name, want := range map[string]string{
+		"a.example.com":     "a",
+		"A.Example.COM.":    "a",
+		"api.b.example.com": "b",
+		"unknown.test":      "a", // типовий — перший
+		"":                  "a",
+	} {
+		cert, err := store.GetCertificate(&tls.ClientHelloInfo{ServerName: name})
+		require.NoError(t, err)
+		require.Equal(t, want, cert.Leaf.Subject.CommonName, name)
+	}
+
+	_, err = newCertStore([]string{aCert}, nil)
+	require.Error(t, err)
+}
+
+func TestCertStore_Reload(t *testing.T) {
+	ca := newTestCA(t)
+	certFile, keyFile := ca.issue(t, "site", "site.example.com")
+	store, err := newCertStore([]string{certFile}, []string{keyFile})
+	require.NoError(t, err)
+	hello := &tls.ClientHelloInfo{ServerName: "site.example.com"}
+	before, _ := store.GetCertificate(hello)
+
+	ca.issue(t, "site", "site.example.com") // перевипуск у ті самі файли
+	require.NoError(t, store.reload())
+	after, _ := store.GetCertificate(hello)
+	require.NotEqual(t, before.Leaf.SerialNumber, after.Leaf.SerialNumber)
+
+	require.NoError(t, os.WriteFile(keyFile, []byte("broken"), 0o600))
+	require.Error(t, store.reload())
+	kept, _ := store.GetCertificate(hello)
+	require.Same(t, after, kept, "a failed reload must keep the current certificate")
+}
+
+func TestTLSTermination(t *testing.T) {
+	ca := newTestCA(t)
+	aCert, aKey := ca.issue(t, "a", "a.example.com")
+	bCert, bKey := ca.issue(t, "b", "b.example.com")
+	cfg, _, err := frontendTLS(aCert+","+bCert, aKey+","+bKey)
+	require.NoError(t, err)
+
+	var proto string
+	plain := useProxyBackend(t, func(rw http.ResponseWriter, r *http.Request) {
+		proto = r.Header.Get("X-Forwarded-Proto")
+	})
+	plain.Close() // потрібен лише бекенд, фронтенд нижче приймає TLS
+
+	frontend := httptest.NewUnstartedServer(http.HandlerFunc(handleRequest))
+	frontend.TLS = cfg
+	frontend.StartTLS()
+	t.Cleanup(frontend.Close)
+
+	client := &http.Client{Transport: &http.Transport{
+		TLSClientConfig: &tls.Config{RootCAs: ca.pool(), ServerName: "b.example.com"},
+	}}
+	resp, err := client.Get(frontend.URL)
+	require.NoError(t, err)
+	resp.Body.Close()
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	require.Equal(t, "b.example.com", resp.TLS.PeerCertificates[0].DNSNames[0])
+	require.Equal(t, "https", proto)
+}
+
+func TestBackendMutualTLS(t *testing.T) {
+	ca := newTestCA(t)
+	serverCert, serverKey := ca.issue(t, "backend", "127.0.0.1")
+	clientCert, clientKey := ca.issue(t, "lb")
+	pair, err := tls.LoadX509KeyPair(serverCert, serverKey)
+	require.NoError(t, err)
+
+	backend := httptest.NewUnstartedServer(http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		_, _ = rw.Write([]byte(r.TLS.PeerCertificates[0].Subject.CommonName))
+	}))
+	backend.TLS = &tls.Config{
+		Certificates: []tls.Certificate{pair},
+		ClientAuth:   tls.RequireAndVerifyClientCert,
+		ClientCAs:    ca.pool(),
+	}
+	backend.StartTLS()
+	t.Cleanup(backend.Close)
+	addr := backend.Listener.Addr().String()
+
+	*https = true
+	t.Cleanup(func() {
+		*https = false
+		backendTLS = nil
+	})
+	send := func() *httptest.ResponseRecorder {
+		useBackends(t)
+		server := &BackendServer{Address: addr, Healthy: true}
+		setTransport(server, resolveTransport(nil))
+		backendStats[addr] = server
+		rec := httptest.NewRecorder()
+		handleRequest(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+		return rec
+	}
+
+	backendTLS, _, err = newBackendTLS(ca.file("ca.pem"), clientCert, clientKey)
+	require.NoError(t, err)
+	rec := send()
+	require.Equal(t, http.StatusOK, rec.Code)
+	require.Equal(t, "lb", rec.Body.String())
+
+	// Без клієнтського сертифіката бекенд розриває рукостискання.
+	backendTLS, _, err = newBackendTLS(ca.file("ca.pem"), "", "")
+	require.NoError(t, err)
+	require.Equal(t, http.StatusBadGateway, send().Code)
+
+	_, _, err = newBackendTLS("", clientCert, "")
+	require.Error(t, err)
+}
@@ -0,0 +1,67 @@
+package main
+
+import (
+	"io"
+	"math"
+	"sync/atomic"
+	"time"
+)
+
+// now підміняється в тестах.
+var now = time.Now
+
+// decayingCounter — експоненційно згасаючий лічильник: внесок кожного
+// значення зменшується вдвічі за halfLife, тож давній трафік не переважає свіжий.
+type decayingCounter struct {
+	value   float64
+	updated time.Time
+}
+
+func (c *decayingCounter) decay(t time.Time, halfLife time.Duration) {
+	if !c.updated.IsZero() && halfLife > 0 {
+		if dt := t.Sub(c.updated); dt > 0 {
+			c.value *= math.Exp2(-float64(dt) / float64(halfLife))
+		}
+	}
+	c.updated = t
+}
+
+func (c *decayingCounter) add(n float64, t time.Time, halfLife time.Duration) {
+	c.decay(t, halfLife)
+	c.value += n
+}
+
+// at повертає значення лічильника на момент t, не змінюючи його.
+func (c *decayingCounter) at(t time.Time, halfLife time.Duration) float64 {
+	cp := *c
+	cp.decay(t, halfLife)
+	return cp.value
+}
+
+// countingReader рахує байти тіла запиту, переданого бекенду. Транспорт може
+// дочитувати тіло у своїй горутині вже після отримання відповіді, тому лічильник атомарний.
+type countingReader struct {
+	io.ReadCloser
+	n atomic.Int64
+}
+
+func (r *countingReader) Read(p []byte) (int, error) {
+	n, err := r.ReadCloser.Read(p)
+	r.n.Add(int64(n))
+	return n, err
+}
+
+// forwardStats — результат одного forward: статус, затримка й обсяг переданих даних.
+type forwardStats struct {
+	StatusCode    int           // 0, якщо бекенд не відповів
+	Latency       time.Duration // час до отримання заголовків відповіді
+	RequestBytes  int64
+	ResponseBytes int64
+}
+
+// recordTraffic оновлює лічильники бекенда після forward. Викликається під mu.
+func recordTraffic(server *BackendServer, st forwardStats) {
+	server.BytesReceived += st.RequestBytes
+	server.BytesSent += st.ResponseBytes
+	server.RecentBytes.add(float64(st.RequestBytes+st.ResponseBytes), now(), *trafficHalfLife)
+}
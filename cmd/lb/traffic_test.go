@@ -0,0 +1,67 @@
+package main
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+// setNow фіксує годинник для тестів і повертає функцію для його зсуву.
+func setNow(t *testing.T) func(d time.Duration) {
+	t.Helper()
+	current := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	now = func() time.Time { return current }
+	t.Cleanup(func() { now = time.Now })
+	return func(d time.Duration) { current = current.Add(d) }
+}
+
+func TestDecayingCounter(t *testing.T) {
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	var c decayingCounter
+
+	c.add(100, start, time.Minute)
+	require.InDelta(t, 100, c.at(start, time.Minute), 1e-9)
+	require.InDelta(t, 50, c.at(start.Add(time.Minute), time.Minute), 1e-9)
+	require.InDelta(t, 25, c.at(start.Add(2*time.Minute), time.Minute), 1e-9)
+
+	c.add(50, start.Add(time.Minute), time.Minute)
+	require.InDelta(t, 100, c.at(start.Add(time.Minute), time.Minute), 1e-9)
+}
+
+func TestLeastBytes_PrefersRecentlyIdle(t *testing.T) {
+	advance := setNow(t)
+	backends := testBackends(2)
+
+	// srv1 колись передав багато даних, srv2 — трохи, але щойно.
+	recordTraffic(backends[0], forwardStats{ResponseBytes: 1 << 20})
+	require.Equal(t, "srv2", leastBytes{}.Choose(nil, backends).Address)
+
+	advance(10 * *trafficHalfLife)
+	recordTraffic(backends[1], forwardStats{ResponseBytes: 10 << 10})
+	require.Equal(t, "srv1", leastBytes{}.Choose(nil, backends).Address)
+
+	// Сумарні лічильники не згасають.
+	require.EqualValues(t, 1<<20, backends[0].BytesSent)
+}
+
+func TestForward_CountsBytes(t *testing.T) {
+	backend := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		_, _ = rw.Write([]byte(strings.Repeat("x", 1000) + string(body)))
+	}))
+	t.Cleanup(backend.Close)
+	timeout = time.Second
+
+	req := httptest.NewRequest(http.MethodPost, "/api", strings.NewReader("hello"))
+	rec := httptest.NewRecorder()
+	st, err := forward(http.DefaultClient, strings.TrimPrefix(backend.URL, "http://"), rec, req)
+	require.NoError(t, err)
+	require.EqualValues(t, 5, st.RequestBytes)
+	require.EqualValues(t, 1005, st.ResponseBytes)
+	require.Equal(t, 1005, rec.Body.Len())
+}
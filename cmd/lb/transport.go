@@ -0,0 +1,205 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	defaultMaxIdleConns = 32
+	defaultIdleTimeout  = 90 * time.Second
+	defaultKeepAlive    = 30 * time.Second
+)
+
+// Transport — параметри пулу з'єднань балансувальника з бекендом. Нульові поля
+// успадковуються від загальних налаштувань або значень за замовчуванням.
+type Transport struct {
+	MaxIdleConns        int      `json:"maxIdleConns,omitempty"` // простоюючі з'єднання, що зберігаються для повторного використання
+	MaxConns            int      `json:"maxConns,omitempty"`     // усі з'єднання з бекендом; 0 — без обмеження
+	IdleTimeout         Duration `json:"idleTimeout,omitempty"`
+	KeepAlive           Duration `json:"keepAlive,omitempty"` // період TCP keep-alive
+	DialTimeout         Duration `json:"dialTimeout,omitempty"`
+	TLSHandshakeTimeout Duration `json:"tlsHandshakeTimeout,omitempty"`
+}
+
+// defaultTransport — загальні налаштування з файлу конфігурації.
+var defaultTransport Transport
+
+// merge повертає копію t, у якій задані поля override мають пріоритет.
+func (t Transport) merge(override *Transport) Transport {
+	if override == nil {
+		return t
+	}
+	if override.MaxIdleConns > 0 {
+		t.MaxIdleConns = override.MaxIdleConns
+	}
+	if override.MaxConns > 0 {
+		t.MaxConns = override.MaxConns
+	}
+	if override.IdleTimeout > 0 {
+		t.IdleTimeout = override.IdleTimeout
+	}
+	if override.KeepAlive > 0 {
+		t.KeepAlive = override.KeepAlive
+	}
+	if override.DialTimeout > 0 {
+		t.DialTimeout = override.DialTimeout
+	}
+	if override.TLSHandshakeTimeout > 0 {
+		t.TLSHandshakeTimeout = override.TLSHandshakeTimeout
+	}
+	return t
+}
+
+func (t *Transport) validate() error {
+	if t == nil {
+		return nil
+	}
+	if t.MaxIdleConns < 0 || t.MaxConns < 0 || t.IdleTimeout < 0 || t.KeepAlive < 0 ||
+		t.DialTimeout < 0 || t.TLSHandshakeTimeout < 0 {
+		return fmt.Errorf("transport settings must not be negative")
+	}
+	return nil
+}
+
+func (t Transport) withDefaults() Transport {
+	return Transport{
+		MaxIdleConns:        defaultMaxIdleConns,
+		IdleTimeout:         Duration(defaultIdleTimeout),
+		KeepAlive:           Duration(defaultKeepAlive),
+		DialTimeout:         Duration(timeout),
+		TLSHandshakeTimeout: Duration(timeout),
+	}.merge(&t)
+}
+
+func resolveTransport(override *Transport) Transport {
+	return defaultTransport.merge(override).withDefaults()
+}
+
+// connPool рахує з'єднання балансувальника з бекендом.
+type connPool struct {
+	open  atomic.Int64 // відкриті з'єднання, активні й простоюючі
+	dials atomic.Int64 // усі встановлені з'єднання
+}
+
+type countedConn struct {
+	net.Conn
+	pool *connPool
+	once sync.Once
+}
+
+func (c *countedConn) Close() error {
+	c.once.Do(func() { c.pool.open.Add(-1) })
+	return c.Conn.Close()
+}
+
+func newHTTPClient(t Transport, pool *connPool) *http.Client {
+	dialer := &net.Dialer{
+		Timeout:   time.Duration(t.DialTimeout),
+		KeepAlive: time.Duration(t.KeepAlive),
+	}
+	return &http.Client{
+		Transport: &http.Transport{
+			DialContext: func(ctx context.Context, network, addr string) (net.Conn, error) {
+				conn, err := dialer.DialContext(ctx, network, addr)
+				if err != nil {
+					return nil, err
+				}
+				pool.dials.Add(1)
+				pool.open.Add(1)
+				return &countedConn{Conn: conn, pool: pool}, nil
+			},
+			MaxIdleConns:        t.MaxIdleConns,
+			MaxIdleConnsPerHost: t.MaxIdleConns,
+			MaxConnsPerHost:     t.MaxConns,
+			IdleConnTimeout:     time.Duration(t.IdleTimeout),
+			TLSHandshakeTimeout: time.Duration(t.TLSHandshakeTimeout),
+			TLSClientConfig:     backendTLS.Clone(),
+		},
+	}
+}
+
+// maxProbeConns — з'єднання для активних перевірок бекенда. Перевірки не ділять
+// пул із запитами, тож не чекають у черзі за MaxConns, коли бекенд зайнятий.
+const maxProbeConns = 1
+
+func newProbeClient(t Transport) *http.Client {
+	dialer := &net.Dialer{
+		Timeout:   time.Duration(t.DialTimeout),
+		KeepAlive: time.Duration(t.KeepAlive),
+	}
+	return &http.Client{
+		Transport: &http.Transport{
+			DialContext:         dialer.DialContext,
+			MaxIdleConnsPerHost: maxProbeConns,
+			MaxConnsPerHost:     maxProbeConns,
+			IdleConnTimeout:     time.Duration(t.IdleTimeout),
+			TLSHandshakeTimeout: time.Duration(t.TLSHandshakeTimeout),
+			TLSClientConfig:     backendTLS.Clone(),
+		},
+	}
+}
+
+// setTransport застосовує до бекенда налаштування пулу з'єднань. Клієнт
+// перестворюється лише тоді, коли вони змінилися. Викликається під mu.
+func setTransport(server *BackendServer, t Transport) {
+	if server.client != nil && server.Transport == t {
+		return
+	}
+	if server.pool == nil {
+		server.pool = &connPool{}
+	}
+	old, oldProbe := server.client, server.probeClient
+	server.Transport = t
+	server.client = newHTTPClient(t, server.pool)
+	server.probeClient = newProbeClient(t)
+	if old != nil {
+		old.CloseIdleConnections()
+		oldProbe.CloseIdleConnections()
+	}
+}
+
+// httpClient повертає клієнт бекенда; бекенди без власного пулу використовують
+// http.DefaultClient. Викликається під mu.
+func (s *BackendServer) httpClient() *http.Client {
+	if s.client == nil {
+		return http.DefaultClient
+	}
+	return s.client
+}
+
+// healthClient повертає клієнт для активних перевірок бекенда. Викликається під mu.
+func (s *BackendServer) healthClient() *http.Client {
+	if s.probeClient == nil {
+		return http.DefaultClient
+	}
+	return s.probeClient
+}
+
+// poolView — використання пулу з'єднань для API адміністрування.
+type poolView struct {
+	Open        int64   `json:"open"`
+	InUse       int64   `json:"inUse"`
+	Idle        int64   `json:"idle"`
+	Dials       int64   `json:"dials"`
+	Utilization float64 `json:"utilization,omitempty"` // InUse/MaxConns, якщо MaxConns задано
+}
+
+// poolOf знімає стан пулу бекенда. Викликається під mu.
+func poolOf(s *BackendServer) poolView {
+	if s.pool == nil {
+		return poolView{}
+	}
+	v := poolView{Open: s.pool.open.Load(), Dials: s.pool.dials.Load()}
+	v.InUse = min(s.ActiveConns, v.Open)
+	v.Idle = v.Open - v.InUse
+	if s.Transport.MaxConns > 0 {
+		v.Utilization = float64(v.InUse) / float64(s.Transport.MaxConns)
+	}
+	return v
+}
@@ -0,0 +1,135 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestTransport_ReusesConnections(t *testing.T) {
+	b := newTestBackend(t)
+	resetBackends(t)
+	require.NoError(t, addBackend(BackendConfig{Address: b.addr(), Transport: &Transport{MaxIdleConns: 4}}))
+	backendStats[b.addr()].Healthy = true
+
+	for _, status := range sendRequests(10) {
+		require.Equal(t, http.StatusOK, status)
+	}
+	view := snapshotBackends()[0]
+	require.Equal(t, 4, view.Transport.MaxIdleConns)
+	require.Equal(t, poolView{Open: 1, Idle: 1, Dials: 1}, view.Connections)
+}
+
+func TestTransport_MaxConns(t *testing.T) {
+	var mu sync.Mutex
+	remotes := make(map[string]bool)
+	backend := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		remotes[r.RemoteAddr] = true
+		mu.Unlock()
+		time.Sleep(20 * time.Millisecond)
+	}))
+	t.Cleanup(backend.Close)
+	addr := backend.Listener.Addr().String()
+
+	resetBackends(t)
+	applyConfig(Config{
+		Transport: &Transport{MaxConns: 1},
+		Backends:  []BackendConfig{{Address: addr}},
+	})
+	backendStats[addr].Healthy = true
+
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			sendRequests(1)
+		}()
+	}
+	wg.Wait()
+	require.Len(t, remotes, 1, "requests must queue for the single allowed connection")
+
+	mu.Lock()
+	defer mu.Unlock()
+	server := backendStats[addr]
+	server.ActiveConns = 1 // як під час запиту
+	require.Equal(t, poolView{Open: 1, InUse: 1, Dials: 1, Utilization: 1}, poolOf(server))
+}
+
+func TestTransport_ProbesBypassMaxConns(t *testing.T) {
+	started, release := make(chan struct{}), make(chan struct{})
+	backend := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == defaultHealthPath {
+			return
+		}
+		close(started)
+		<-release
+	}))
+	t.Cleanup(backend.Close)
+	addr := backend.Listener.Addr().String()
+
+	timeout = 5 * time.Second
+	resetBackends(t)
+	applyConfig(Config{
+		Transport: &Transport{MaxConns: 1},
+		Backends:  []BackendConfig{{Address: addr}},
+	})
+	backendStats[addr].Healthy = true
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		sendRequests(1)
+	}()
+	<-started
+	t.Cleanup(func() {
+		close(release)
+		<-done
+	})
+
+	// Єдине з'єднання пулу зайняте запитом, але перевірка від нього не залежить.
+	mu.Lock()
+	server := backendStats[addr]
+	shared, probes := server.httpClient(), server.healthClient()
+	mu.Unlock()
+	hc := HealthCheck{Timeout: Duration(100 * time.Millisecond)}.withDefaults()
+	require.Error(t, probe(shared, addr, hc))
+	require.NoError(t, probe(probes, addr, hc))
+}
+
+func TestTransport_ReloadKeepsUnchangedClient(t *testing.T) {
+	resetBackends(t)
+	t.Cleanup(func() { defaultTransport = Transport{} })
+	cfg := Config{Backends: []BackendConfig{{Address: "a:80", Transport: &Transport{MaxConns: 8}}}}
+	applyConfig(cfg)
+	server := backendStats["a:80"]
+	client := server.client
+	require.Equal(t, 8, server.Transport.MaxConns)
+	require.Equal(t, defaultMaxIdleConns, server.Transport.MaxIdleConns)
+
+	applyConfig(cfg)
+	require.Same(t, client, server.client)
+
+	cfg.Transport = &Transport{MaxIdleConns: 2}
+	applyConfig(cfg)
+	require.NotSame(t, client, server.client)
+	require.Equal(t, Transport{
+		MaxIdleConns:        2,
+		MaxConns:            8,
+		IdleTimeout:         Duration(defaultIdleTimeout),
+		KeepAlive:           Duration(defaultKeepAlive),
+		DialTimeout:         Duration(timeout),
+		TLSHandshakeTimeout: Duration(timeout),
+	}, server.Transport)
+}
+
+func TestTransport_Validate(t *testing.T) {
+	require.NoError(t, (*Transport)(nil).validate())
+	require.Error(t, Config{Transport: &Transport{MaxConns: -1}}.validate())
+	require.Error(t, Config{Backends: []BackendConfig{{Address: "a", Transport: &Transport{DialTimeout: -1}}}}.validate())
+}
@@ -1,35 +1,39 @@
 package main
 
 import (
-	"encoding/json"
+	"context"
+	"errors"
 	"flag"
-	"fmt"
 	"log"
 	"net/http"
 	"os"
 	"strconv"
-	"strings"
 	"time"
 
+	"github.com/roman-mazur/architecture-practice-4-template/dbclient"
+	"github.com/roman-mazur/architecture-practice-4-template/hashring"
 	"github.com/roman-mazur/architecture-practice-4-template/httptools"
 	"github.com/roman-mazur/architecture-practice-4-template/signal"
 )
 
 var port = flag.Int("port", 8080, "server port")
-var dbAddress = flag.String("db-addr", "db:8070", "DB service address")
+var dbAddress = flag.String("db-addr", "db:8070", "comma-separated list of DB service addresses")
+var dbReplicas = flag.Int("db-replicas", hashring.DefaultReplicas, "virtual nodes per DB address on the hash ring")
 
 const confResponseDelaySec = "CONF_RESPONSE_DELAY_SEC"
 const confHealthFailure = "CONF_HEALTH_FAILURE"
 
 const teamName = "sophisticated_operations_on_software_architecture_labs"
 
-type DbResponse struct {
-	Key   string `json:"key"`
-	Value string `json:"value"`
-}
+const dbTimeout = 5 * time.Second
+
+const shutdownTimeout = 10 * time.Second
+
+var db *dbclient.Cluster
 
 func main() {
 	flag.Parse()
+	db = dbclient.NewCluster(*dbReplicas, dbclient.ParseNodes(*dbAddress), dbclient.WithTimeout(dbTimeout))
 
 	currentDate := time.Now().Format("2006-01-02")
 	storeInitialData(teamName, currentDate)
@@ -63,11 +67,15 @@ func main() {
 			return
 		}
 
-		value, err := fetchFromDb(key)
-		if err != nil {
+		value, err := fetchFromDb(r.Context(), key)
+		if errors.Is(err, dbclient.ErrNotFound) {
 			rw.WriteHeader(http.StatusNotFound)
 			return
 		}
+		if err != nil {
+			rw.WriteHeader(http.StatusInternalServerError)
+			return
+		}
 
 		rw.Header().Set("content-type", "application/json")
 		rw.WriteHeader(http.StatusOK)
@@ -79,51 +87,27 @@ func main() {
 	server := httptools.CreateServer(*port, h)
 	server.Start()
 	signal.WaitForTerminationSignal()
+	if err := server.Shutdown(shutdownTimeout); err != nil {
+		log.Printf("Graceful shutdown interrupted: %s", err)
+	}
 }
 
 func storeInitialData(key, value string) {
-	url := fmt.Sprintf("http://%s/db/%s", *dbAddress, key)
-	
-	payload := fmt.Sprintf(`{"value": "%s"}`, value)
-	
-	req, err := http.NewRequest("POST", url, strings.NewReader(payload))
-	if err != nil {
-		log.Printf("Error creating request to DB: %s", err)
-		return
-	}
-	
-	req.Header.Set("Content-Type", "application/json")
-	
-	client := &http.Client{}
-	resp, err := client.Do(req)
-	if err != nil {
+	ctx, cancel := context.WithTimeout(context.Background(), dbTimeout)
+	defer cancel()
+
+	if err := db.Put(ctx, key, value); err != nil {
 		log.Printf("Failed to store initial data in DB: %s", err)
 		return
 	}
-	defer resp.Body.Close()
-	
-	log.Printf("Stored initial data in DB, status: %d", resp.StatusCode)
+	log.Printf("Stored initial data in DB node %s", db.NodeFor(key))
 }
 
-func fetchFromDb(key string) (string, error) {
-	url := fmt.Sprintf("http://%s/db/%s", *dbAddress, key)
-	
-	resp, err := http.Get(url)
+func fetchFromDb(ctx context.Context, key string) (string, error) {
+	value, err := db.Get(ctx, key)
 	if err != nil {
 		log.Printf("Error fetching from DB: %s", err)
 		return "", err
 	}
-	defer resp.Body.Close()
-	
-	if resp.StatusCode != http.StatusOK {
-		return "", fmt.Errorf("DB service returned status %d", resp.StatusCode)
-	}
-	
-	var dbResp DbResponse
-	if err := json.NewDecoder(resp.Body).Decode(&dbResp); err != nil {
-		log.Printf("Error decoding DB response: %s", err)
-		return "", err
-	}
-	
-	return dbResp.Value, nil
-}
\ No newline at end of file
+	return value, nil
+}
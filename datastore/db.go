@@ -42,9 +42,12 @@ var ErrNotFound = fmt.Errorf("record does not exist")
 // ------------------------------------------------------------
 
 type writeRequest struct {
-	key   string
-	value string
-	done  chan error
+	key      string
+	value    string
+	deleted  bool
+	existed  *bool // якщо задано, writer повідомляє, чи був ключ в індексі до запису
+	ifAbsent bool  // записати лише відсутній ключ; інакше writer нічого не пише
+	done     chan error
 }
 
 type getRequest struct {
@@ -81,13 +84,45 @@ type Db struct {
 	// async readers pool
 	getCh chan getRequest
 	getWg sync.WaitGroup
+
+	hooks Hooks
+}
+
+// Hooks — необов'язкові колбеки для спостереження за роботою Db (метрики тощо).
+// Викликаються синхронно, тож мають бути швидкими.
+type Hooks struct {
+	OnPut     func(d time.Duration, err error)
+	OnGet     func(d time.Duration, err error)
+	OnDelete  func(d time.Duration, err error)
+	OnRotate  func(d time.Duration, err error)
+	OnCompact func(d time.Duration, err error)
+}
+
+func observe(hook func(time.Duration, error), start time.Time, err error) {
+	if hook != nil {
+		hook(time.Since(start), err)
+	}
+}
+
+type Option func(*Db)
+
+// WithHooks підключає колбеки спостереження.
+func WithHooks(h Hooks) Option {
+	return func(db *Db) { db.hooks = h }
+}
+
+// Stats — знімок стану сховища.
+type Stats struct {
+	Keys         int   // кількість ключів в індексі
+	Segments     int   // кількість файлів-сегментів разом з активним
+	SegmentBytes int64 // сумарний розмір сегментів
 }
 
 // ------------------------------------------------------------
 // API
 // ------------------------------------------------------------
 
-func Open(dir string) (*Db, error) {
+func Open(dir string, opts ...Option) (*Db, error) {
 	if err := os.MkdirAll(dir, 0o755); err != nil {
 		return nil, err
 	}
@@ -114,6 +149,9 @@ func Open(dir string) (*Db, error) {
 		getCh:       make(chan getRequest, 128),
 		maxSegBytes: int64(maxSize),
 	}
+	for _, opt := range opts {
+		opt(db)
+	}
 
 	// Відновлюємо індекс з усіх сегментів
 	if err := db.recoverAll(); err != nil {
@@ -135,15 +173,49 @@ func Open(dir string) (*Db, error) {
 }
 
 func (db *Db) Put(key, value string) error {
+	_, err := db.Upsert(key, value)
+	return err
+}
+
+// Upsert записує значення, як Put, і повідомляє, чи існував ключ до запису.
+// Перевірка й запис відбуваються атомарно щодо інших записів.
+func (db *Db) Upsert(key, value string) (existed bool, err error) {
+	start := time.Now()
 	done := make(chan error, 1)
-	db.writeCh <- writeRequest{key: key, value: value, done: done}
-	return <-done
+	db.writeCh <- writeRequest{key: key, value: value, existed: &existed, done: done}
+	err = <-done
+	observe(db.hooks.OnPut, start, err)
+	return existed, err
+}
+
+// PutIfAbsent записує значення, лише якщо ключа ще немає, і повідомляє, чи
+// записано його. Перевірка й запис відбуваються атомарно щодо інших записів.
+func (db *Db) PutIfAbsent(key, value string) (stored bool, err error) {
+	start := time.Now()
+	done := make(chan error, 1)
+	var existed bool
+	db.writeCh <- writeRequest{key: key, value: value, existed: &existed, ifAbsent: true, done: done}
+	err = <-done
+	observe(db.hooks.OnPut, start, err)
+	return err == nil && !existed, err
+}
+
+// Delete видаляє ключ, дописуючи tombstone-запис у активний сегмент.
+func (db *Db) Delete(key string) error {
+	start := time.Now()
+	done := make(chan error, 1)
+	db.writeCh <- writeRequest{key: key, deleted: true, done: done}
+	err := <-done
+	observe(db.hooks.OnDelete, start, err)
+	return err
 }
 
 func (db *Db) Get(key string) (string, error) {
+	start := time.Now()
 	resp := make(chan getResult, 1)
 	db.getCh <- getRequest{key: key, response: resp}
 	r := <-resp
+	observe(db.hooks.OnGet, start, r.err)
 	return r.value, r.err
 }
 
@@ -156,6 +228,30 @@ func (db *Db) Size() (int64, error) {
 	return info.Size(), nil
 }
 
+// Stats рахує ключі в індексі та розміри файлів-сегментів.
+func (db *Db) Stats() (Stats, error) {
+	db.indexMu.RLock()
+	keys := len(db.index)
+	db.indexMu.RUnlock()
+
+	files, err := filepath.Glob(filepath.Join(db.dir, closedPattern))
+	if err != nil {
+		return Stats{}, err
+	}
+	files = append(files, filepath.Join(db.dir, activeFileName))
+
+	st := Stats{Keys: keys}
+	for _, f := range files {
+		info, err := os.Stat(f)
+		if err != nil {
+			continue // сегмент могли прибрати під час компакції
+		}
+		st.Segments++
+		st.SegmentBytes += info.Size()
+	}
+	return st, nil
+}
+
 func (db *Db) Close() error {
 	close(db.writeCh)
 	db.wg.Wait()
@@ -167,7 +263,10 @@ func (db *Db) Close() error {
 }
 
 // Compact запускає компакцію закритих сегментів (active не чіпає).
-func (db *Db) Compact() error {
+func (db *Db) Compact() (err error) {
+	start := time.Now()
+	defer func() { observe(db.hooks.OnCompact, start, err) }()
+
 	// 1. Тимчасово зупиняємо прийом записів: закриваємо старий канал і чекаємо.
 	close(db.writeCh)
 	db.wg.Wait()
@@ -266,8 +365,23 @@ func (db *Db) restartWriter() {
 func (db *Db) backgroundWriter() {
 	defer db.wg.Done()
 	for req := range db.writeCh {
+		if req.deleted || req.ifAbsent {
+			db.indexMu.RLock()
+			_, ok := db.index[req.key]
+			db.indexMu.RUnlock()
+			if req.deleted && !ok {
+				req.done <- ErrNotFound
+				continue
+			}
+			if req.ifAbsent && ok {
+				*req.existed = true
+				req.done <- nil
+				continue
+			}
+		}
+
 		// 1. Кодуємо entry.
-		e := entry{key: req.key, value: req.value}
+		e := entry{key: req.key, value: req.value, deleted: req.deleted}
 		data := e.Encode()
 
 		// 2. Записуємо.
@@ -275,7 +389,14 @@ func (db *Db) backgroundWriter() {
 		n, err := db.out.Write(data)
 		if err == nil {
 			db.indexMu.Lock()
-			db.index[req.key] = segPointer{file: db.out.Name(), offset: pos}
+			if req.existed != nil {
+				_, *req.existed = db.index[req.key]
+			}
+			if req.deleted {
+				delete(db.index, req.key)
+			} else {
+				db.index[req.key] = segPointer{file: db.out.Name(), offset: pos}
+			}
 			db.outOffset += int64(n)
 			db.indexMu.Unlock()
 		}
@@ -283,7 +404,9 @@ func (db *Db) backgroundWriter() {
 
 		// 3. Перевіряємо, чи треба робити ротацію.
 		if db.outOffset >= db.maxSegBytes {
-			_ = db.rotateSegment()
+			start := time.Now()
+			err := db.rotateSegment()
+			observe(db.hooks.OnRotate, start, err)
 		}
 	}
 }
@@ -299,10 +422,20 @@ func (db *Db) rotateSegment() error {
 	}
 
 	// Перенеймовуємо «current-data» у «segment-<ts>.seg».
+	activePath := filepath.Join(db.dir, activeFileName)
 	newName := filepath.Join(db.dir, fmt.Sprintf("segment-%d.seg", time.Now().UnixNano()))
-	if err := os.Rename(filepath.Join(db.dir, activeFileName), newName); err != nil {
+	// Ключі, записані в active, тепер лежать у перейменованому сегменті.
+	db.indexMu.Lock()
+	if err := os.Rename(activePath, newName); err != nil {
+		db.indexMu.Unlock()
 		return err
 	}
+	for k, p := range db.index {
+		if p.file == activePath {
+			db.index[k] = segPointer{file: newName, offset: p.offset}
+		}
+	}
+	db.indexMu.Unlock()
 
 	// Відкриваємо новий current-data
 	f, err := os.OpenFile(filepath.Join(db.dir, activeFileName), os.O_CREATE|os.O_APPEND|os.O_RDWR, 0o600)
@@ -398,7 +531,11 @@ func (db *Db) recoverFile(path string) error {
 		if err != nil {
 			return err
 		}
-		db.index[e.key] = segPointer{file: path, offset: offset}
+		if e.deleted {
+			delete(db.index, e.key)
+		} else {
+			db.index[e.key] = segPointer{file: path, offset: offset}
+		}
 		offset += int64(n)
 	}
 
@@ -1,6 +1,7 @@
 package datastore
 
 import (
+	"errors"
 	"testing"
 )
 
@@ -57,6 +58,54 @@ func TestDb(t *testing.T) {
 		}
 	})
 
+	t.Run("delete", func(t *testing.T) {
+		if err := db.Put("to-delete", "v"); err != nil {
+			t.Fatal(err)
+		}
+		if err := db.Delete("to-delete"); err != nil {
+			t.Fatalf("Cannot delete: %s", err)
+		}
+		if _, err := db.Get("to-delete"); !errors.Is(err, ErrNotFound) {
+			t.Errorf("Expected ErrNotFound after delete, got %v", err)
+		}
+		if err := db.Delete("to-delete"); !errors.Is(err, ErrNotFound) {
+			t.Errorf("Expected ErrNotFound for repeated delete, got %v", err)
+		}
+	})
+
+	t.Run("upsert", func(t *testing.T) {
+		for i, want := range []bool{false, true} {
+			existed, err := db.Upsert("upserted", "v")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if existed != want {
+				t.Errorf("Upsert #%d reported existed=%t, wanted %t", i+1, existed, want)
+			}
+		}
+		if err := db.Delete("upserted"); err != nil {
+			t.Fatal(err)
+		}
+		if existed, err := db.Upsert("upserted", "v"); err != nil || existed {
+			t.Errorf("Upsert after delete = %t, %v; wanted false, nil", existed, err)
+		}
+	})
+
+	t.Run("put if absent", func(t *testing.T) {
+		if stored, err := db.PutIfAbsent("once", "first"); err != nil || !stored {
+			t.Fatalf("PutIfAbsent of a new key = %t, %v", stored, err)
+		}
+		if stored, err := db.PutIfAbsent("once", "second"); err != nil || stored {
+			t.Fatalf("PutIfAbsent of an existing key = %t, %v", stored, err)
+		}
+		if value, err := db.Get("once"); err != nil || value != "first" {
+			t.Errorf("Get() = %q, %v; wanted the first value", value, err)
+		}
+		if err := db.Delete("once"); err != nil {
+			t.Fatal(err)
+		}
+	})
+
 	t.Run("new db process", func(t *testing.T) {
 		if err := db.Close(); err != nil {
 			t.Fatal(err)
@@ -71,6 +120,10 @@ func TestDb(t *testing.T) {
 			uniquePairs[pair[0]] = pair[1]
 		}
 
+		if _, err := db.Get("to-delete"); !errors.Is(err, ErrNotFound) {
+			t.Errorf("Deleted key is back after reopen: %v", err)
+		}
+
 		for key, expectedValue := range uniquePairs {
 			value, err := db.Get(key)
 			if err != nil {
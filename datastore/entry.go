@@ -6,23 +6,36 @@ import (
 	"errors"
 	"fmt"
 	"io"
+	"math"
 )
 
 type entry struct {
 	key, value string
+	deleted    bool // tombstone: ключ видалено
 }
 
 // 0           4    8     kl+8  kl+12     <-- offset
 // (full size) (kl) (key) (vl)  (value)
 // 4           4    ....  4     .....     <-- length
+//
+// Для tombstone-запису vl дорівнює tombstoneLen, а значення відсутнє.
+
+const tombstoneLen = math.MaxUint32
 
 func (e *entry) Encode() []byte {
 	kl, vl := len(e.key), len(e.value)
+	if e.deleted {
+		vl = 0
+	}
 	size := kl + vl + 12
 	res := make([]byte, size)
 	binary.LittleEndian.PutUint32(res, uint32(size))
 	binary.LittleEndian.PutUint32(res[4:], uint32(kl))
 	copy(res[8:], e.key)
+	if e.deleted {
+		binary.LittleEndian.PutUint32(res[kl+8:], tombstoneLen)
+		return res
+	}
 	binary.LittleEndian.PutUint32(res[kl+8:], uint32(vl))
 	copy(res[kl+12:], e.value)
 	return res
@@ -30,7 +43,13 @@ func (e *entry) Encode() []byte {
 
 func (e *entry) Decode(input []byte) {
 	e.key = decodeString(input[4:])
-	e.value = decodeString(input[len(e.key)+8:])
+	vOffset := len(e.key) + 8
+	if binary.LittleEndian.Uint32(input[vOffset:]) == tombstoneLen {
+		e.value, e.deleted = "", true
+		return
+	}
+	e.value = decodeString(input[vOffset:])
+	e.deleted = false
 }
 
 func decodeString(v []byte) string {
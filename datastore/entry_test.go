@@ -7,7 +7,7 @@ import (
 )
 
 func TestEntry_Encode(t *testing.T) {
-	e := entry{"key", "value"}
+	e := entry{key: "key", value: "value"}
 	e.Decode(e.Encode())
 	if e.key != "key" {
 		t.Error("incorrect key")
@@ -21,7 +21,7 @@ func TestReadValue(t *testing.T) {
 	var (
 		a, b entry
 	)
-	a = entry{"key", "test-value"}
+	a = entry{key: "key", value: "test-value"}
 	originalBytes := a.Encode()
 
 	b.Decode(originalBytes)
@@ -43,3 +43,18 @@ func TestReadValue(t *testing.T) {
 		t.Errorf("DecodeFromReader() read %d bytes, expected %d", n, len(originalBytes))
 	}
 }
+
+func TestEntry_Tombstone(t *testing.T) {
+	a := entry{key: "key", deleted: true}
+	var b entry
+	n, err := b.DecodeFromReader(bufio.NewReader(bytes.NewReader(a.Encode())))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if a != b {
+		t.Errorf("tombstone mismatch: %v vs %v", a, b)
+	}
+	if n != len(a.Encode()) {
+		t.Errorf("DecodeFromReader() read %d bytes, expected %d", n, len(a.Encode()))
+	}
+}
@@ -127,3 +127,87 @@ func directorySize(t *testing.T, dir string) (total int64) {
 	})
 	return
 }
+
+// TestGetAfterRotation перевіряє, що ключі, записані до ротації, читаються
+// з перейменованого сегмента, а не з нового порожнього активного файла.
+func TestGetAfterRotation(t *testing.T) {
+	setMaxSegmentSize(t)
+
+	db, err := Open(t.TempDir())
+	if err != nil {
+		t.Fatalf("open db: %v", err)
+	}
+	defer db.Close()
+
+	for i := 0; i < 15; i++ {
+		if err := db.Put("key-"+strconv.Itoa(i), testValue+strconv.Itoa(i)); err != nil {
+			t.Fatalf("put: %v", err)
+		}
+	}
+	drainWrites()
+
+	for i := 0; i < 15; i++ {
+		got, err := db.Get("key-" + strconv.Itoa(i))
+		if err != nil {
+			t.Fatalf("get key-%d: %v", i, err)
+		}
+		if want := testValue + strconv.Itoa(i); got != want {
+			t.Errorf("get key-%d = %q, want %q", i, got, want)
+		}
+	}
+}
+
+// TestHooksAndStats перевіряє, що колбеки викликаються для всіх операцій,
+// а Stats бачить ключі та всі сегменти.
+func TestHooksAndStats(t *testing.T) {
+	setMaxSegmentSize(t)
+
+	var puts, gets, rotations, compactions int
+	var getErrs int
+	db, err := Open(t.TempDir(), WithHooks(Hooks{
+		OnPut: func(time.Duration, error) { puts++ },
+		OnGet: func(_ time.Duration, err error) {
+			gets++
+			if err != nil {
+				getErrs++
+			}
+		},
+		OnRotate:  func(time.Duration, error) { rotations++ },
+		OnCompact: func(time.Duration, error) { compactions++ },
+	}))
+	if err != nil {
+		t.Fatalf("open db: %v", err)
+	}
+	defer db.Close()
+
+	for i := 0; i < 15; i++ {
+		if err := db.Put("key-"+strconv.Itoa(i), testValue); err != nil {
+			t.Fatalf("put: %v", err)
+		}
+	}
+	_, _ = db.Get("key-1")
+	_, _ = db.Get("missing")
+	drainWrites()
+
+	st, err := db.Stats()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if st.Keys != 15 {
+		t.Errorf("Stats().Keys = %d, want 15", st.Keys)
+	}
+	if st.Segments < 2 || st.SegmentBytes == 0 {
+		t.Errorf("unexpected segment stats: %+v", st)
+	}
+
+	if err := db.Compact(); err != nil {
+		t.Fatalf("compact: %v", err)
+	}
+
+	if puts != 15 || gets != 2 || getErrs != 1 {
+		t.Errorf("unexpected hook calls: puts=%d gets=%d getErrs=%d", puts, gets, getErrs)
+	}
+	if rotations == 0 || compactions != 1 {
+		t.Errorf("unexpected hook calls: rotations=%d compactions=%d", rotations, compactions)
+	}
+}
@@ -0,0 +1,240 @@
+package dbclient
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"time"
+)
+
+const (
+	defaultTimeout = 10 * time.Second
+	defaultRetries = 2
+	defaultBackoff = 100 * time.Millisecond
+)
+
+var ErrNotFound = errors.New("record does not exist")
+
+// ErrExists повертає PutIfAbsent, якщо ключ уже є на вузлі.
+var ErrExists = errors.New("record already exists")
+
+// StatusError — неочікувана відповідь вузла cmd/db.
+type StatusError struct {
+	Node       string
+	StatusCode int
+	Code       string // код помилки з тіла відповіді, якщо він є
+	Message    string
+}
+
+func (e *StatusError) Error() string {
+	if e.Code == "" {
+		return fmt.Sprintf("DB node %s returned status %d", e.Node, e.StatusCode)
+	}
+	return fmt.Sprintf("DB node %s returned status %d (%s): %s", e.Node, e.StatusCode, e.Code, e.Message)
+}
+
+type errorBody struct {
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
+func newStatusError(node string, resp *http.Response) *StatusError {
+	e := &StatusError{Node: node, StatusCode: resp.StatusCode}
+	var body errorBody
+	if json.NewDecoder(io.LimitReader(resp.Body, 4096)).Decode(&body) == nil {
+		e.Code, e.Message = body.Code, body.Message
+	}
+	return e
+}
+
+// Client — типізований клієнт HTTP API одного вузла cmd/db.
+type Client struct {
+	addr    string
+	http    *http.Client
+	retries int
+	backoff time.Duration
+}
+
+type Option func(*Client)
+
+// WithTimeout обмежує час одного HTTP-запиту (без урахування повторів).
+func WithTimeout(d time.Duration) Option {
+	return func(c *Client) { c.http.Timeout = d }
+}
+
+// WithRetries задає кількість повторів після мережевих помилок і 5xx
+// та початкову затримку, яка подвоюється з кожною спробою.
+func WithRetries(retries int, backoff time.Duration) Option {
+	return func(c *Client) {
+		c.retries = retries
+		c.backoff = backoff
+	}
+}
+
+// WithHTTPClient підміняє HTTP-клієнт, наприклад для власного transport.
+func WithHTTPClient(hc *http.Client) Option {
+	return func(c *Client) { c.http = hc }
+}
+
+// New створює клієнт для вузла за адресою host:port.
+func New(addr string, opts ...Option) *Client {
+	c := &Client{
+		addr:    addr,
+		http:    &http.Client{Timeout: defaultTimeout},
+		retries: defaultRetries,
+		backoff: defaultBackoff,
+	}
+	for _, opt := range opts {
+		opt(c)
+	}
+	return c
+}
+
+// Addr повертає адресу вузла.
+func (c *Client) Addr() string {
+	return c.addr
+}
+
+type valueBody struct {
+	Key   string `json:"key,omitempty"`
+	Value string `json:"value"`
+}
+
+// Op — одна операція пакета: запис значення або видалення ключа.
+type Op struct {
+	Key    string
+	Value  string
+	Delete bool
+}
+
+// BatchError повідомляє, на якій операції пакета виникла помилка.
+type BatchError struct {
+	Index int
+	Err   error
+}
+
+func (e *BatchError) Error() string {
+	return fmt.Sprintf("batch operation %d: %s", e.Index, e.Err)
+}
+
+func (e *BatchError) Unwrap() error {
+	return e.Err
+}
+
+func (c *Client) Get(ctx context.Context, key string) (string, error) {
+	var body valueBody
+	err := c.do(ctx, http.MethodGet, key, nil, nil, func(resp *http.Response) error {
+		return json.NewDecoder(resp.Body).Decode(&body)
+	})
+	if err != nil {
+		return "", err
+	}
+	return body.Value, nil
+}
+
+func (c *Client) Put(ctx context.Context, key, value string) error {
+	payload, err := json.Marshal(valueBody{Value: value})
+	if err != nil {
+		return err
+	}
+	return c.do(ctx, http.MethodPost, key, nil, payload, nil)
+}
+
+// PutIfAbsent записує значення, лише якщо ключа на вузлі ще немає; інакше
+// повертає ErrExists і не змінює збережене значення.
+func (c *Client) PutIfAbsent(ctx context.Context, key, value string) error {
+	payload, err := json.Marshal(valueBody{Value: value})
+	if err != nil {
+		return err
+	}
+	return c.do(ctx, http.MethodPut, key, http.Header{"If-None-Match": {"*"}}, payload, nil)
+}
+
+func (c *Client) Delete(ctx context.Context, key string) error {
+	return c.do(ctx, http.MethodDelete, key, nil, nil, nil)
+}
+
+// Batch виконує операції по черзі й зупиняється на першій помилці.
+func (c *Client) Batch(ctx context.Context, ops []Op) error {
+	for i, op := range ops {
+		var err error
+		if op.Delete {
+			err = c.Delete(ctx, op.Key)
+		} else {
+			err = c.Put(ctx, op.Key, op.Value)
+		}
+		if err != nil {
+			return &BatchError{Index: i, Err: err}
+		}
+	}
+	return nil
+}
+
+func (c *Client) url(key string) string {
+	return fmt.Sprintf("http://%s/db/%s", c.addr, url.PathEscape(key))
+}
+
+// do виконує запит із повторами; decode викликається лише для успішної відповіді.
+func (c *Client) do(ctx context.Context, method, key string, header http.Header, payload []byte, decode func(*http.Response) error) error {
+	backoff := c.backoff
+	var err error
+	for attempt := 0; ; attempt++ {
+		var retry bool
+		retry, err = c.try(ctx, method, key, header, payload, decode)
+		if !retry || attempt >= c.retries {
+			return err
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(backoff):
+		}
+		backoff *= 2
+	}
+}
+
+func (c *Client) try(ctx context.Context, method, key string, header http.Header, payload []byte, decode func(*http.Response) error) (retry bool, err error) {
+	var body io.Reader
+	if payload != nil {
+		body = bytes.NewReader(payload)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, c.url(key), body)
+	if err != nil {
+		return false, err
+	}
+	for name, values := range header {
+		req.Header[name] = values
+	}
+	if payload != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	resp, err := c.http.Do(req)
+	if err != nil {
+		// Скасований контекст повторювати немає сенсу.
+		return ctx.Err() == nil, err
+	}
+	defer resp.Body.Close()
+
+	switch {
+	case resp.StatusCode == http.StatusNotFound:
+		return false, ErrNotFound
+	case resp.StatusCode == http.StatusPreconditionFailed:
+		return false, ErrExists
+	case resp.StatusCode >= 500:
+		return true, newStatusError(c.addr, resp)
+	case resp.StatusCode < 200 || resp.StatusCode >= 300:
+		return false, newStatusError(c.addr, resp)
+	}
+
+	if decode != nil {
+		return false, decode(resp)
+	}
+	return false, nil
+}
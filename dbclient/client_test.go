@@ -0,0 +1,153 @@
+package dbclient
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestClient_CRUD(t *testing.T) {
+	db, addr := startFakeDb(t)
+	c := New(addr)
+	ctx := context.Background()
+
+	value := `quotes " and \ backslashes`
+	if err := c.Put(ctx, "key", value); err != nil {
+		t.Fatalf("put: %s", err)
+	}
+	got, err := c.Get(ctx, "key")
+	if err != nil || got != value {
+		t.Fatalf("get = %q, %v; want %q", got, err, value)
+	}
+
+	if err := c.Delete(ctx, "key"); err != nil {
+		t.Fatalf("delete: %s", err)
+	}
+	if _, err := c.Get(ctx, "key"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("expected ErrNotFound after delete, got %v", err)
+	}
+	if err := c.Delete(ctx, "key"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("expected ErrNotFound for repeated delete, got %v", err)
+	}
+	if db.len() != 0 {
+		t.Errorf("expected empty storage, got %d records", db.len())
+	}
+}
+
+func TestClient_Batch(t *testing.T) {
+	db, addr := startFakeDb(t)
+	c := New(addr)
+	ctx := context.Background()
+
+	err := c.Batch(ctx, []Op{
+		{Key: "a", Value: "1"},
+		{Key: "b", Value: "2"},
+		{Key: "a", Delete: true},
+	})
+	if err != nil {
+		t.Fatalf("batch: %s", err)
+	}
+	if db.len() != 1 {
+		t.Errorf("expected 1 record after batch, got %d", db.len())
+	}
+
+	err = c.Batch(ctx, []Op{
+		{Key: "c", Value: "3"},
+		{Key: "missing", Delete: true},
+		{Key: "d", Value: "4"},
+	})
+	var batchErr *BatchError
+	if !errors.As(err, &batchErr) || batchErr.Index != 1 || !errors.Is(err, ErrNotFound) {
+		t.Fatalf("unexpected batch error: %v", err)
+	}
+	if _, err := c.Get(ctx, "d"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("batch did not stop at the failed operation")
+	}
+}
+
+func TestClient_RetriesServerErrors(t *testing.T) {
+	var calls atomic.Int32
+	db := &fakeDb{data: map[string]string{"key": "value"}}
+	srv := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		if calls.Add(1) <= 2 {
+			rw.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		db.ServeHTTP(rw, r)
+	}))
+	t.Cleanup(srv.Close)
+
+	c := New(strings.TrimPrefix(srv.URL, "http://"), WithRetries(2, time.Millisecond))
+	value, err := c.Get(context.Background(), "key")
+	if err != nil || value != "value" {
+		t.Fatalf("get = %q, %v", value, err)
+	}
+	if calls.Load() != 3 {
+		t.Errorf("expected 3 attempts, got %d", calls.Load())
+	}
+
+	calls.Store(0)
+	c = New(strings.TrimPrefix(srv.URL, "http://"), WithRetries(1, time.Millisecond))
+	_, err = c.Get(context.Background(), "key")
+	var statusErr *StatusError
+	if !errors.As(err, &statusErr) || statusErr.StatusCode != http.StatusServiceUnavailable {
+		t.Errorf("expected StatusError 503, got %v", err)
+	}
+}
+
+func TestClient_DoesNotRetryClientErrors(t *testing.T) {
+	var calls atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		rw.Header().Set("Content-Type", "application/json")
+		rw.WriteHeader(http.StatusBadRequest)
+		_, _ = rw.Write([]byte(`{"code": "invalid_body", "message": "bad"}`))
+	}))
+	t.Cleanup(srv.Close)
+
+	c := New(strings.TrimPrefix(srv.URL, "http://"), WithRetries(3, time.Millisecond))
+	err := c.Put(context.Background(), "key", "value")
+	var statusErr *StatusError
+	if !errors.As(err, &statusErr) || statusErr.Code != "invalid_body" || statusErr.Message != "bad" {
+		t.Fatalf("expected StatusError with decoded body, got %v", err)
+	}
+	if calls.Load() != 1 {
+		t.Errorf("expected a single attempt, got %d", calls.Load())
+	}
+}
+
+func TestClient_TimeoutAndContext(t *testing.T) {
+	release := make(chan struct{})
+	srv := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		select {
+		case <-release:
+		case <-r.Context().Done():
+		}
+	}))
+	t.Cleanup(func() {
+		close(release)
+		srv.Close()
+	})
+	addr := strings.TrimPrefix(srv.URL, "http://")
+
+	c := New(addr, WithTimeout(20*time.Millisecond), WithRetries(0, 0))
+	if _, err := c.Get(context.Background(), "key"); err == nil {
+		t.Error("expected timeout error")
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	c = New(addr, WithRetries(5, time.Second))
+	start := time.Now()
+	if _, err := c.Get(ctx, "key"); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("expected context deadline error, got %v", err)
+	}
+	if time.Since(start) > time.Second {
+		t.Errorf("retries ignored the context deadline")
+	}
+}
@@ -0,0 +1,195 @@
+package dbclient
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"slices"
+	"strings"
+	"sync"
+
+	"github.com/roman-mazur/architecture-practice-4-template/hashring"
+)
+
+// Cluster розподіляє ключі між кількома вузлами cmd/db за допомогою
+// консистентного хешування.
+type Cluster struct {
+	opts []Option
+
+	mu      sync.RWMutex
+	clients map[string]*Client
+	ring    *hashring.Ring
+	// history — кільця до кожного додавання вузла, від новішого до старішого.
+	// Ключ переноситься лише під час читання, тож після кількох додавань поспіль
+	// він може лежати на власнику будь-якого з попередніх кілець.
+	history []*hashring.Ring
+}
+
+// maxRingHistory — скільки попередніх кілець зберігає Cluster. Ключі, які не
+// читали протягом стількох додавань вузлів, стають недоступними, зате промах
+// не опитує необмежену кількість колишніх власників.
+const maxRingHistory = 4
+
+// NewCluster створює клієнт для набору вузлів із replicas віртуальних вузлів на кожен.
+// opts застосовуються до клієнта кожного вузла.
+func NewCluster(replicas int, nodes []string, opts ...Option) *Cluster {
+	c := &Cluster{
+		opts:    opts,
+		clients: make(map[string]*Client),
+		ring:    hashring.New(replicas),
+	}
+	for _, n := range nodes {
+		c.clients[n] = New(n, opts...)
+	}
+	c.ring.Add(nodes...)
+	return c
+}
+
+// ParseNodes розбирає список адрес, розділених комами.
+func ParseNodes(list string) []string {
+	var nodes []string
+	for _, n := range strings.Split(list, ",") {
+		if n = strings.TrimSpace(n); n != "" {
+			nodes = append(nodes, n)
+		}
+	}
+	return nodes
+}
+
+// Nodes повертає поточний список вузлів.
+func (c *Cluster) Nodes() []string {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.ring.Nodes()
+}
+
+// NodeFor повертає вузол, відповідальний за ключ.
+func (c *Cluster) NodeFor(key string) string {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.ring.Get(key)
+}
+
+// AddNode додає вузол до кластера. Ключі, які тепер належать новому вузлу,
+// переносяться на нього під час першого читання.
+func (c *Cluster) AddNode(node string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if _, ok := c.clients[node]; ok {
+		return
+	}
+	c.clients[node] = New(node, c.opts...)
+	c.history = append([]*hashring.Ring{c.ring.Clone()}, c.history...)
+	if len(c.history) > maxRingHistory {
+		log.Printf("Keys not read since %d node additions ago are no longer looked up", maxRingHistory)
+		c.history = c.history[:maxRingHistory]
+	}
+	c.ring.Add(node)
+}
+
+// RemoveNode прибирає вузол із кластера. Дані на ньому стають недоступними.
+func (c *Cluster) RemoveNode(node string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	delete(c.clients, node)
+	c.ring.Remove(node)
+	for _, r := range c.history {
+		r.Remove(node)
+	}
+}
+
+// owners повертає поточного власника ключа й попередніх власників з історії
+// кілець (без повторів і без поточного), від новіших до старіших.
+func (c *Cluster) owners(key string) (current *Client, previous []*Client, err error) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	current = c.clients[c.ring.Get(key)]
+	if current == nil {
+		return nil, nil, fmt.Errorf("no DB nodes configured")
+	}
+	for _, r := range c.history {
+		prev := c.clients[r.Get(key)]
+		if prev != nil && prev != current && !slices.Contains(previous, prev) {
+			previous = append(previous, prev)
+		}
+	}
+	return current, previous, nil
+}
+
+func (c *Cluster) Get(ctx context.Context, key string) (string, error) {
+	node, prevNodes, err := c.owners(key)
+	if err != nil {
+		return "", err
+	}
+
+	value, err := node.Get(ctx, key)
+	if !errors.Is(err, ErrNotFound) {
+		return value, err
+	}
+
+	// Ключ ще не перенесено після зміни топології — шукаємо на попередніх власниках.
+	for _, prevNode := range prevNodes {
+		value, err = prevNode.Get(ctx, key)
+		if errors.Is(err, ErrNotFound) {
+			continue
+		}
+		if err != nil {
+			return "", err
+		}
+		// Поки значення читалося, на нового власника міг потрапити новіший запис —
+		// тоді переносити нічого, а застаріла копія лише видаляється.
+		err = node.PutIfAbsent(ctx, key, value)
+		if errors.Is(err, ErrExists) {
+			if value, err = node.Get(ctx, key); err != nil {
+				return "", err
+			}
+		} else if err != nil {
+			log.Printf("Failed to move key %s from %s to %s: %s", key, prevNode.Addr(), node.Addr(), err)
+			return value, nil
+		}
+		if err := prevNode.Delete(ctx, key); err != nil && !errors.Is(err, ErrNotFound) {
+			log.Printf("Failed to delete moved key %s from %s: %s", key, prevNode.Addr(), err)
+		}
+		return value, nil
+	}
+	return "", err
+}
+
+func (c *Cluster) Put(ctx context.Context, key, value string) error {
+	node, _, err := c.owners(key)
+	if err != nil {
+		return err
+	}
+	return node.Put(ctx, key, value)
+}
+
+// Delete видаляє ключ і з поточного, і з усіх попередніх власників.
+func (c *Cluster) Delete(ctx context.Context, key string) error {
+	node, prevNodes, err := c.owners(key)
+	if err != nil {
+		return err
+	}
+	err = node.Delete(ctx, key)
+	found := err == nil
+	var errs []error
+	if !errors.Is(err, ErrNotFound) && err != nil {
+		errs = append(errs, err)
+	}
+	for _, prevNode := range prevNodes {
+		prevErr := prevNode.Delete(ctx, key)
+		switch {
+		case prevErr == nil:
+			found = true
+		case !errors.Is(prevErr, ErrNotFound):
+			errs = append(errs, prevErr)
+		}
+	}
+	if len(errs) > 0 {
+		return errors.Join(errs...)
+	}
+	if !found {
+		return ErrNotFound
+	}
+	return nil
+}
@@ -0,0 +1,276 @@
+package dbclient
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// fakeDb імітує HTTP API cmd/db поверх map у пам'яті.
+type fakeDb struct {
+	mu   sync.Mutex
+	data map[string]string
+	// afterGet, якщо задано, викликається після читання ключа, ще до відповіді.
+	afterGet func(key string)
+}
+
+func (f *fakeDb) ServeHTTP(rw http.ResponseWriter, r *http.Request) {
+	key := strings.TrimPrefix(r.URL.Path, "/db/")
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	switch r.Method {
+	case http.MethodGet:
+		value, ok := f.data[key]
+		if !ok {
+			http.Error(rw, "Not found", http.StatusNotFound)
+			return
+		}
+		if f.afterGet != nil {
+			f.afterGet(key)
+		}
+		_ = json.NewEncoder(rw).Encode(valueBody{Key: key, Value: value})
+	case http.MethodPost, http.MethodPut:
+		var body valueBody
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			http.Error(rw, "Invalid request body", http.StatusBadRequest)
+			return
+		}
+		if _, ok := f.data[key]; ok && r.Header.Get("If-None-Match") == "*" {
+			http.Error(rw, "Exists", http.StatusPreconditionFailed)
+			return
+		}
+		f.data[key] = body.Value
+	case http.MethodDelete:
+		if _, ok := f.data[key]; !ok {
+			http.Error(rw, "Not found", http.StatusNotFound)
+			return
+		}
+		delete(f.data, key)
+	default:
+		http.Error(rw, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+func (f *fakeDb) len() int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return len(f.data)
+}
+
+func startFakeDb(t *testing.T) (*fakeDb, string) {
+	t.Helper()
+	db := &fakeDb{data: make(map[string]string)}
+	srv := httptest.NewServer(db)
+	t.Cleanup(srv.Close)
+	return db, strings.TrimPrefix(srv.URL, "http://")
+}
+
+func TestCluster_PutGet(t *testing.T) {
+	var nodes []string
+	var dbs []*fakeDb
+	for i := 0; i < 3; i++ {
+		db, addr := startFakeDb(t)
+		dbs = append(dbs, db)
+		nodes = append(nodes, addr)
+	}
+	c := NewCluster(0, nodes)
+	ctx := context.Background()
+
+	const n = 300
+	for i := 0; i < n; i++ {
+		key := "key-" + strconv.Itoa(i)
+		if err := c.Put(ctx, key, `"quoted" value `+key); err != nil {
+			t.Fatalf("put %s: %s", key, err)
+		}
+	}
+	for i := 0; i < n; i++ {
+		key := "key-" + strconv.Itoa(i)
+		value, err := c.Get(ctx, key)
+		if err != nil {
+			t.Fatalf("get %s: %s", key, err)
+		}
+		if value != `"quoted" value `+key {
+			t.Errorf("get %s = %q", key, value)
+		}
+	}
+
+	total := 0
+	for i, db := range dbs {
+		if db.len() == 0 {
+			t.Errorf("node %d received no keys", i)
+		}
+		total += db.len()
+	}
+	if total != n {
+		t.Errorf("expected every key to be stored once, got %d records for %d keys", total, n)
+	}
+
+	if _, err := c.Get(ctx, "missing"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("expected ErrNotFound, got %v", err)
+	}
+}
+
+func TestCluster_AddNodeRebalances(t *testing.T) {
+	dbA, a := startFakeDb(t)
+	dbB, b := startFakeDb(t)
+	newDb, newAddr := startFakeDb(t)
+	c := NewCluster(0, []string{a, b})
+	ctx := context.Background()
+
+	const n = 200
+	for i := 0; i < n; i++ {
+		if err := c.Put(ctx, "key-"+strconv.Itoa(i), strconv.Itoa(i)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	c.AddNode(newAddr)
+
+	moved := 0
+	for i := 0; i < n; i++ {
+		key := "key-" + strconv.Itoa(i)
+		value, err := c.Get(ctx, key)
+		if err != nil || value != strconv.Itoa(i) {
+			t.Fatalf("get %s after adding node: %q, %v", key, value, err)
+		}
+		if c.NodeFor(key) == newAddr {
+			moved++
+		}
+	}
+	if moved == 0 {
+		t.Fatal("no keys are owned by the new node")
+	}
+	if newDb.len() != moved {
+		t.Errorf("expected %d keys moved to the new node, got %d", moved, newDb.len())
+	}
+	if total := dbA.len() + dbB.len() + newDb.len(); total != n {
+		t.Errorf("expected moved keys to be removed from old nodes, got %d records for %d keys", total, n)
+	}
+}
+
+func TestCluster_TwoAdditionsInARow(t *testing.T) {
+	dbA, a := startFakeDb(t)
+	c := NewCluster(0, []string{a})
+	ctx := context.Background()
+
+	const n = 200
+	for i := 0; i < n; i++ {
+		if err := c.Put(ctx, "key-"+strconv.Itoa(i), strconv.Itoa(i)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// Між додаваннями ключі не читаються, тож усі вони ще лежать на першому вузлі.
+	dbB, b := startFakeDb(t)
+	dbC, cAddr := startFakeDb(t)
+	c.AddNode(b)
+	c.AddNode(cAddr)
+
+	for i := 0; i < n; i++ {
+		key := "key-" + strconv.Itoa(i)
+		value, err := c.Get(ctx, key)
+		if err != nil || value != strconv.Itoa(i) {
+			t.Fatalf("get %s after adding two nodes: %q, %v", key, value, err)
+		}
+	}
+	if dbB.len() == 0 || dbC.len() == 0 {
+		t.Errorf("expected keys to move to both new nodes, got %d and %d", dbB.len(), dbC.len())
+	}
+	if total := dbA.len() + dbB.len() + dbC.len(); total != n {
+		t.Errorf("expected every key to be stored once, got %d records for %d keys", total, n)
+	}
+
+	// Видалення знаходить ключ, що ще не переїхав.
+	if err := c.Put(ctx, "late", "v"); err != nil {
+		t.Fatal(err)
+	}
+	_, d := startFakeDb(t)
+	c.AddNode(d)
+	if err := c.Delete(ctx, "late"); err != nil {
+		t.Fatalf("delete: %v", err)
+	}
+	if _, err := c.Get(ctx, "late"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("expected ErrNotFound after delete, got %v", err)
+	}
+	if err := c.Delete(ctx, "late"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("expected ErrNotFound for a missing key, got %v", err)
+	}
+}
+
+func TestCluster_MoveDoesNotOverwriteNewerPut(t *testing.T) {
+	dbA, a := startFakeDb(t)
+	c := NewCluster(0, []string{a})
+	ctx := context.Background()
+
+	// Ключ, який після додавання вузла належатиме новому власнику.
+	_, b := startFakeDb(t)
+	probe := NewCluster(0, []string{a, b})
+	key := ""
+	for i := 0; key == ""; i++ {
+		if k := "key-" + strconv.Itoa(i); probe.NodeFor(k) == b {
+			key = k
+		}
+	}
+	if err := c.Put(ctx, key, "old"); err != nil {
+		t.Fatal(err)
+	}
+	c.AddNode(b)
+
+	// Новий запис потрапляє на нового власника між читанням старого значення й перенесенням.
+	dbA.afterGet = func(string) {
+		dbA.afterGet = nil
+		if err := c.Put(ctx, key, "new"); err != nil {
+			t.Error(err)
+		}
+	}
+	if value, err := c.Get(ctx, key); err != nil || value != "new" {
+		t.Fatalf("get during a concurrent put = %q, %v", value, err)
+	}
+	if value, err := c.Get(ctx, key); err != nil || value != "new" {
+		t.Errorf("the move overwrote a newer value: %q, %v", value, err)
+	}
+	if dbA.len() != 0 {
+		t.Errorf("expected the stale copy to be removed from the old owner")
+	}
+}
+
+func TestCluster_HistoryIsBounded(t *testing.T) {
+	_, a := startFakeDb(t)
+	c := NewCluster(0, []string{a})
+	for i := 0; i < maxRingHistory+3; i++ {
+		c.AddNode("node-" + strconv.Itoa(i) + ":80")
+	}
+	if len(c.history) != maxRingHistory {
+		t.Errorf("expected %d ring generations, got %d", maxRingHistory, len(c.history))
+	}
+}
+
+func TestCluster_Delete(t *testing.T) {
+	_, a := startFakeDb(t)
+	c := NewCluster(0, []string{a})
+	ctx := context.Background()
+
+	if err := c.Put(ctx, "k", "v"); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.Delete(ctx, "k"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := c.Get(ctx, "k"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("expected ErrNotFound after delete, got %v", err)
+	}
+}
+
+func TestParseNodes(t *testing.T) {
+	nodes := ParseNodes(" db1:8070, ,db2:8070")
+	if len(nodes) != 2 || nodes[0] != "db1:8070" || nodes[1] != "db2:8070" {
+		t.Errorf("unexpected nodes: %v", nodes)
+	}
+}
@@ -0,0 +1,113 @@
+package dbproto
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"net"
+	"sync"
+	"time"
+)
+
+var ErrNotFound = errors.New("record does not exist")
+
+// ErrBroken повертається після збою посеред обміну: непрочитані відповіді
+// лишилися в з'єднанні, тож воно закрите й більше не використовується.
+var ErrBroken = errors.New("connection is unusable after an earlier error")
+
+// Client — з'єднання з бінарним портом cmd/db. Безпечний для конкурентного
+// використання, але запити на одному з'єднанні виконуються послідовно.
+type Client struct {
+	mu   sync.Mutex
+	conn net.Conn
+	r    *bufio.Reader
+	w    *bufio.Writer
+	err  error // перша помилка обміну; після неї клієнт непридатний
+}
+
+func Dial(addr string, timeout time.Duration) (*Client, error) {
+	conn, err := net.DialTimeout("tcp", addr, timeout)
+	if err != nil {
+		return nil, err
+	}
+	return &Client{
+		conn: conn,
+		r:    bufio.NewReader(conn),
+		w:    bufio.NewWriter(conn),
+	}, nil
+}
+
+func (c *Client) Close() error {
+	return c.conn.Close()
+}
+
+// Pipeline надсилає всі запити одним пакетом і читає відповіді в тому ж порядку.
+func (c *Client) Pipeline(reqs []Request) ([]Response, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrBroken, c.err)
+	}
+
+	for i := range reqs {
+		if _, err := c.w.Write(reqs[i].Encode()); err != nil {
+			return nil, c.fail(err)
+		}
+	}
+	if err := c.w.Flush(); err != nil {
+		return nil, c.fail(err)
+	}
+
+	res := make([]Response, len(reqs))
+	for i := range res {
+		resp, err := ReadResponse(c.r)
+		if err != nil {
+			return nil, c.fail(err)
+		}
+		res[i] = resp
+	}
+	return res, nil
+}
+
+// fail запам'ятовує помилку й закриває з'єднання: наступна відповідь у ньому
+// вже не відповідатиме наступному запиту. Викликається під mu.
+func (c *Client) fail(err error) error {
+	c.err = err
+	_ = c.conn.Close()
+	return err
+}
+
+func (c *Client) do(req Request) (Response, error) {
+	res, err := c.Pipeline([]Request{req})
+	if err != nil {
+		return Response{}, err
+	}
+	return res[0], res[0].Err()
+}
+
+func (c *Client) Get(key string) (string, error) {
+	resp, err := c.do(Request{Op: OpGet, Key: key})
+	return resp.Value, err
+}
+
+func (c *Client) Put(key, value string) error {
+	_, err := c.do(Request{Op: OpPut, Key: key, Value: value})
+	return err
+}
+
+func (c *Client) Delete(key string) error {
+	_, err := c.do(Request{Op: OpDelete, Key: key})
+	return err
+}
+
+// Err перетворює статус відповіді на помилку.
+func (r *Response) Err() error {
+	switch r.Status {
+	case StatusOK:
+		return nil
+	case StatusNotFound:
+		return ErrNotFound
+	default:
+		return fmt.Errorf("DB error: %s", r.Value)
+	}
+}
@@ -0,0 +1,139 @@
+package dbproto
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// Бінарний протокол cmd/db: кадри з префіксом довжини поверх TCP.
+// Клієнт може надсилати кілька запитів, не чекаючи відповідей (pipelining);
+// сервер відповідає строго в порядку надходження.
+//
+// Запит:
+// 0           4    5    9     kl+9  kl+13     <-- offset
+// (full size) (op) (kl) (key) (vl)  (value)
+// 4           1    4    ....  4     .....     <-- length
+//
+// Відповідь:
+// 0           4        5    9         <-- offset
+// (full size) (status) (vl) (value)
+// 4           1        4    .....     <-- length
+
+const (
+	OpGet    byte = 'G'
+	OpPut    byte = 'P'
+	OpDelete byte = 'D'
+)
+
+const (
+	StatusOK byte = iota
+	StatusNotFound
+	StatusError
+)
+
+// MaxFrameSize обмежує розмір кадру, щоб зіпсований потік не виділяв гігабайти.
+const MaxFrameSize = 64 << 20
+
+var ErrFrameTooLarge = errors.New("frame is too large")
+
+type Request struct {
+	Op         byte
+	Key, Value string
+}
+
+type Response struct {
+	Status byte
+	Value  string // значення для OpGet або текст помилки для StatusError
+}
+
+func (r *Request) Encode() []byte {
+	kl, vl := len(r.Key), len(r.Value)
+	size := kl + vl + 13
+	res := make([]byte, size)
+	binary.LittleEndian.PutUint32(res, uint32(size))
+	res[4] = r.Op
+	binary.LittleEndian.PutUint32(res[5:], uint32(kl))
+	copy(res[9:], r.Key)
+	binary.LittleEndian.PutUint32(res[kl+9:], uint32(vl))
+	copy(res[kl+13:], r.Value)
+	return res
+}
+
+func (r *Response) Encode() []byte {
+	vl := len(r.Value)
+	size := vl + 9
+	res := make([]byte, size)
+	binary.LittleEndian.PutUint32(res, uint32(size))
+	res[4] = r.Status
+	binary.LittleEndian.PutUint32(res[5:], uint32(vl))
+	copy(res[9:], r.Value)
+	return res
+}
+
+// ReadRequest читає один кадр запиту. Повертає io.EOF, якщо потік закрито між кадрами.
+func ReadRequest(in *bufio.Reader) (Request, error) {
+	var req Request
+	frame, err := readFrame(in, 13)
+	if err != nil {
+		return req, err
+	}
+	req.Op = frame[4]
+	key, rest, err := decodeString(frame[5:])
+	if err != nil {
+		return req, err
+	}
+	value, _, err := decodeString(rest)
+	if err != nil {
+		return req, err
+	}
+	req.Key, req.Value = key, value
+	return req, nil
+}
+
+// ReadResponse читає один кадр відповіді.
+func ReadResponse(in *bufio.Reader) (Response, error) {
+	var resp Response
+	frame, err := readFrame(in, 9)
+	if err != nil {
+		return resp, err
+	}
+	resp.Status = frame[4]
+	resp.Value, _, err = decodeString(frame[5:])
+	return resp, err
+}
+
+func readFrame(in *bufio.Reader, minSize int) ([]byte, error) {
+	sizeBuf, err := in.Peek(4)
+	if err != nil {
+		if errors.Is(err, io.EOF) && len(sizeBuf) == 0 {
+			return nil, io.EOF
+		}
+		return nil, fmt.Errorf("cannot read frame size: %w", err)
+	}
+	size := int(binary.LittleEndian.Uint32(sizeBuf))
+	if size < minSize {
+		return nil, fmt.Errorf("frame size %d is too small", size)
+	}
+	if size > MaxFrameSize {
+		return nil, ErrFrameTooLarge
+	}
+	frame := make([]byte, size)
+	if _, err := io.ReadFull(in, frame); err != nil {
+		return nil, fmt.Errorf("cannot read frame: %w", err)
+	}
+	return frame, nil
+}
+
+func decodeString(v []byte) (string, []byte, error) {
+	if len(v) < 4 {
+		return "", nil, fmt.Errorf("malformed frame")
+	}
+	l := int(binary.LittleEndian.Uint32(v))
+	if l > len(v)-4 {
+		return "", nil, fmt.Errorf("malformed frame")
+	}
+	return string(v[4 : 4+l]), v[4+l:], nil
+}
@@ -0,0 +1,107 @@
+package dbproto
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"io"
+	"net"
+	"testing"
+	"time"
+)
+
+func TestRequest_Encode(t *testing.T) {
+	var buf bytes.Buffer
+	reqs := []Request{
+		{Op: OpPut, Key: "key", Value: "value"},
+		{Op: OpGet, Key: "key"},
+		{Op: OpDelete, Key: ""},
+	}
+	for i := range reqs {
+		buf.Write(reqs[i].Encode())
+	}
+
+	r := bufio.NewReader(&buf)
+	for _, expected := range reqs {
+		got, err := ReadRequest(r)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got != expected {
+			t.Errorf("ReadRequest() = %v, expected %v", got, expected)
+		}
+	}
+	if _, err := ReadRequest(r); !errors.Is(err, io.EOF) {
+		t.Errorf("expected io.EOF at the end of stream, got %v", err)
+	}
+}
+
+func TestResponse_Encode(t *testing.T) {
+	expected := Response{Status: StatusError, Value: "boom"}
+	got, err := ReadResponse(bufio.NewReader(bytes.NewReader(expected.Encode())))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got != expected {
+		t.Errorf("ReadResponse() = %v, expected %v", got, expected)
+	}
+}
+
+func TestReadRequest_Malformed(t *testing.T) {
+	req := Request{Op: OpPut, Key: "key", Value: "value"}
+	data := req.Encode()
+	binary.LittleEndian.PutUint32(data[5:], 1000) // довжина ключа виходить за межі кадру
+	if _, err := ReadRequest(bufio.NewReader(bytes.NewReader(data))); err == nil {
+		t.Error("expected error for malformed key length")
+	}
+
+	data = req.Encode()
+	if _, err := ReadRequest(bufio.NewReader(bytes.NewReader(data[:len(data)-1]))); err == nil || errors.Is(err, io.EOF) {
+		t.Errorf("expected truncated frame error, got %v", err)
+	}
+
+	huge := make([]byte, 4)
+	binary.LittleEndian.PutUint32(huge, MaxFrameSize+1)
+	if _, err := ReadRequest(bufio.NewReader(bytes.NewReader(huge))); !errors.Is(err, ErrFrameTooLarge) {
+		t.Errorf("expected ErrFrameTooLarge, got %v", err)
+	}
+}
+
+func TestClient_BrokenAfterPartialPipeline(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+	// Сервер відповідає лише на перший запит конвеєра й обриває з'єднання.
+	go func() {
+		conn, err := ln.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		r := bufio.NewReader(conn)
+		for i := 0; i < 2; i++ {
+			if _, err := ReadRequest(r); err != nil {
+				return
+			}
+		}
+		resp := Response{Status: StatusOK, Value: "first"}
+		_, _ = conn.Write(resp.Encode())
+	}()
+
+	c, err := Dial(ln.Addr().String(), time.Second)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+
+	_, err = c.Pipeline([]Request{{Op: OpGet, Key: "a"}, {Op: OpGet, Key: "b"}})
+	if err == nil {
+		t.Fatal("expected an error for a truncated pipeline")
+	}
+	if _, err := c.Get("a"); !errors.Is(err, ErrBroken) {
+		t.Errorf("expected ErrBroken after a failed pipeline, got %v", err)
+	}
+}
@@ -0,0 +1,141 @@
+package hashring
+
+import (
+	"hash/crc32"
+	"sort"
+	"strconv"
+	"sync"
+)
+
+// DefaultReplicas — кількість віртуальних вузлів на один фізичний вузол.
+const DefaultReplicas = 100
+
+// Ring — кільце консистентного хешування з віртуальними вузлами.
+type Ring struct {
+	replicas int
+
+	mu     sync.RWMutex
+	hashes []uint32          // відсортовані хеші віртуальних вузлів
+	owners map[uint32]string // хеш віртуального вузла -> фізичний вузол
+	nodes  map[string]struct{}
+}
+
+// New створює кільце; replicas <= 0 означає DefaultReplicas.
+func New(replicas int, nodes ...string) *Ring {
+	if replicas <= 0 {
+		replicas = DefaultReplicas
+	}
+	r := &Ring{
+		replicas: replicas,
+		owners:   make(map[uint32]string),
+		nodes:    make(map[string]struct{}),
+	}
+	r.Add(nodes...)
+	return r
+}
+
+func hashKey(key string) uint32 {
+	return crc32.ChecksumIEEE([]byte(key))
+}
+
+func virtualKey(node string, i int) string {
+	return strconv.Itoa(i) + "#" + node
+}
+
+// Add додає вузли до кільця. Повторне додавання вузла ігнорується.
+func (r *Ring) Add(nodes ...string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, node := range nodes {
+		if _, ok := r.nodes[node]; ok {
+			continue
+		}
+		r.nodes[node] = struct{}{}
+		for i := 0; i < r.replicas; i++ {
+			h := hashKey(virtualKey(node, i))
+			if _, taken := r.owners[h]; taken {
+				continue // колізія — залишаємо першого власника
+			}
+			r.owners[h] = node
+			r.hashes = append(r.hashes, h)
+		}
+	}
+	sort.Slice(r.hashes, func(i, j int) bool { return r.hashes[i] < r.hashes[j] })
+}
+
+// Remove прибирає вузол і всі його віртуальні вузли.
+func (r *Ring) Remove(node string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.nodes[node]; !ok {
+		return
+	}
+	delete(r.nodes, node)
+	hashes := r.hashes[:0]
+	for _, h := range r.hashes {
+		if r.owners[h] == node {
+			delete(r.owners, h)
+			continue
+		}
+		hashes = append(hashes, h)
+	}
+	r.hashes = hashes
+}
+
+// Get повертає вузол, відповідальний за ключ, або "" для порожнього кільця.
+func (r *Ring) Get(key string) string {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	if len(r.hashes) == 0 {
+		return ""
+	}
+	h := hashKey(key)
+	i := sort.Search(len(r.hashes), func(i int) bool { return r.hashes[i] >= h })
+	if i == len(r.hashes) {
+		i = 0
+	}
+	return r.owners[r.hashes[i]]
+}
+
+// Nodes повертає відсортований список фізичних вузлів.
+func (r *Ring) Nodes() []string {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	res := make([]string, 0, len(r.nodes))
+	for n := range r.nodes {
+		res = append(res, n)
+	}
+	sort.Strings(res)
+	return res
+}
+
+// Len повертає кількість фізичних вузлів.
+func (r *Ring) Len() int {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return len(r.nodes)
+}
+
+// Clone повертає незалежну копію кільця.
+func (r *Ring) Clone() *Ring {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	c := &Ring{
+		replicas: r.replicas,
+		hashes:   append([]uint32(nil), r.hashes...),
+		owners:   make(map[uint32]string, len(r.owners)),
+		nodes:    make(map[string]struct{}, len(r.nodes)),
+	}
+	for h, n := range r.owners {
+		c.owners[h] = n
+	}
+	for n := range r.nodes {
+		c.nodes[n] = struct{}{}
+	}
+	return c
+}
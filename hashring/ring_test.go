@@ -0,0 +1,73 @@
+package hashring
+
+import (
+	"strconv"
+	"testing"
+)
+
+func TestRing_Get(t *testing.T) {
+	r := New(0)
+	if n := r.Get("key"); n != "" {
+		t.Errorf("empty ring returned node %q", n)
+	}
+
+	r.Add("a", "b", "c")
+	for i := 0; i < 100; i++ {
+		key := "key-" + strconv.Itoa(i)
+		if first, second := r.Get(key), r.Get(key); first != second || first == "" {
+			t.Fatalf("unstable owner for %s: %q vs %q", key, first, second)
+		}
+	}
+}
+
+func TestRing_Distribution(t *testing.T) {
+	r := New(DefaultReplicas, "a", "b", "c")
+
+	const keys = 30000
+	counts := make(map[string]int)
+	for i := 0; i < keys; i++ {
+		counts[r.Get("key-"+strconv.Itoa(i))]++
+	}
+	// Очікуємо ~1/3 на вузол; допускаємо значне відхилення.
+	for _, n := range r.Nodes() {
+		if counts[n] < keys/6 || counts[n] > keys/2 {
+			t.Errorf("node %s owns %d of %d keys", n, counts[n], keys)
+		}
+	}
+}
+
+func TestRing_AddMovesOnlyToNewNode(t *testing.T) {
+	r := New(DefaultReplicas, "a", "b", "c")
+	before := r.Clone()
+	r.Add("d")
+
+	moved := 0
+	const keys = 10000
+	for i := 0; i < keys; i++ {
+		key := "key-" + strconv.Itoa(i)
+		was, now := before.Get(key), r.Get(key)
+		if was == now {
+			continue
+		}
+		if now != "d" {
+			t.Fatalf("key %s moved from %s to %s, not to the new node", key, was, now)
+		}
+		moved++
+	}
+	if moved == 0 || moved > keys/2 {
+		t.Errorf("unexpected number of moved keys: %d of %d", moved, keys)
+	}
+}
+
+func TestRing_Remove(t *testing.T) {
+	r := New(10, "a", "b")
+	r.Remove("a")
+	if r.Len() != 1 {
+		t.Fatalf("expected 1 node, got %d", r.Len())
+	}
+	for i := 0; i < 100; i++ {
+		if n := r.Get(strconv.Itoa(i)); n != "b" {
+			t.Fatalf("key routed to %q after removal", n)
+		}
+	}
+}
@@ -1,14 +1,21 @@
 package httptools
 
 import (
+	"context"
+	"crypto/tls"
+	"errors"
 	"fmt"
 	"log"
+	"net"
 	"net/http"
 	"time"
 )
 
 type Server interface {
 	Start()
+	// Shutdown припиняє приймати нові з'єднання й чекає завершення поточних
+	// запитів не довше за timeout.
+	Shutdown(timeout time.Duration) error
 }
 
 type server struct {
@@ -18,19 +25,79 @@ type server struct {
 func (s server) Start() {
 	go func() {
 		log.Println("Staring the HTTP server...")
-		err := s.httpServer.ListenAndServe()
+		var err error
+		if s.httpServer.TLSConfig != nil {
+			// Сертифікати надає TLSConfig, тож файли не передаються.
+			err = s.httpServer.ListenAndServeTLS("", "")
+		} else {
+			err = s.httpServer.ListenAndServe()
+		}
+		if errors.Is(err, http.ErrServerClosed) {
+			log.Println("HTTP server stopped accepting new connections.")
+			return
+		}
 		log.Fatalf("HTTP server finished: %s. Finishing the process.", err)
 	}()
 }
 
-func CreateServer(port int, handler http.Handler) Server {
-	return server{
-		httpServer: &http.Server{
-			Addr:           fmt.Sprintf(":%d", port),
-			Handler:        handler,
-			ReadTimeout:    10 * time.Second,
-			WriteTimeout:   10 * time.Second,
-			MaxHeaderBytes: 1 << 20,
-		},
+func (s server) Shutdown(timeout time.Duration) error {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	return s.httpServer.Shutdown(ctx)
+}
+
+// Option змінює налаштування сервера за замовчуванням.
+type Option func(*http.Server)
+
+// WithHost обмежує сервер однією адресою, наприклад 127.0.0.1. За замовчуванням
+// сервер слухає на всіх інтерфейсах.
+func WithHost(host string) Option {
+	return func(s *http.Server) {
+		_, port, _ := net.SplitHostPort(s.Addr)
+		s.Addr = net.JoinHostPort(host, port)
+	}
+}
+
+// WithTimeouts замінює ліміти на читання запиту й запис відповіді; 0 вимикає
+// відповідний ліміт. Заголовки все одно мають надійти за ReadHeaderTimeout.
+func WithTimeouts(read, write time.Duration) Option {
+	return func(s *http.Server) {
+		s.ReadTimeout = read
+		s.WriteTimeout = write
+	}
+}
+
+// WithIdleTimeout задає, скільки keep-alive з'єднання може простоювати між
+// запитами. Без нього простій обмежує ReadTimeout, а якщо й він 0 — ніщо.
+func WithIdleTimeout(d time.Duration) Option {
+	return func(s *http.Server) {
+		s.IdleTimeout = d
+	}
+}
+
+func CreateServer(port int, handler http.Handler, opts ...Option) Server {
+	return server{httpServer: newHTTPServer(port, handler, opts)}
+}
+
+// CreateTLSServer створює сервер, що приймає лише TLS-з'єднання; сертифікати
+// мають бути задані в tlsConfig, наприклад через GetCertificate.
+func CreateTLSServer(port int, handler http.Handler, tlsConfig *tls.Config, opts ...Option) Server {
+	s := newHTTPServer(port, handler, opts)
+	s.TLSConfig = tlsConfig
+	return server{httpServer: s}
+}
+
+func newHTTPServer(port int, handler http.Handler, opts []Option) *http.Server {
+	s := &http.Server{
+		Addr:              fmt.Sprintf(":%d", port),
+		Handler:           handler,
+		ReadTimeout:       10 * time.Second,
+		ReadHeaderTimeout: 10 * time.Second,
+		WriteTimeout:      10 * time.Second,
+		MaxHeaderBytes:    1 << 20,
+	}
+	for _, opt := range opts {
+		opt(s)
 	}
+	return s
 }
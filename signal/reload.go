@@ -0,0 +1,20 @@
+package signal
+
+import (
+	"log"
+	"os"
+	"os/signal"
+	"syscall"
+)
+
+// OnReloadSignal викликає fn у фоні на кожен SIGHUP.
+func OnReloadSignal(fn func()) {
+	hupChannel := make(chan os.Signal, 1)
+	signal.Notify(hupChannel, syscall.SIGHUP)
+	go func() {
+		for range hupChannel {
+			log.Println("Reloading configuration...")
+			fn()
+		}
+	}()
+}